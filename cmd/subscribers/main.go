@@ -0,0 +1,385 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+// Command subscribers serves and administers the subscribers API.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/marcanthonyconcepcion/MarcGoRESTAPIDemo"
+)
+
+const usage = `Usage: subscribers <command> [--config file] [flags]
+
+Commands:
+  serve                   serve the API until SIGINT or SIGTERM
+  migrate up              apply all pending migrations
+  migrate down [--steps]  roll back the last migration, or the last steps migrations
+  migrate status          show the schema version
+  seed                    create the sample subscribers
+  export [--output file]  write the subscribers as NDJSON, to standard output by default
+  import [--input file]   create the subscribers of an NDJSON export, from standard input by default
+  check-config            validate the configuration without connecting to the database
+
+Every command reads its configuration from --config, ` + MarcGoRESTAPIDemo.DefaultConfigurationFile + ` by default.
+`
+
+const exportPageSize = 100
+
+var sampleSubscribers = []MarcGoRESTAPIDemo.Subscriber{
+	{EmailAddress: "marcanthonyconcepcion@gmail.com", FirstName: "Marc Anthony", LastName: "Concepcion"},
+	{EmailAddress: "marcanthonyconcepcion@email.com", FirstName: "Marc", LastName: "Concepcion"},
+	{EmailAddress: "kevin.andrews@email.com", FirstName: "Kevin", LastName: "Andrews"},
+}
+
+// errUsage is reported by commands that were called wrong, after they explained their usage.
+var errUsage = errors.New("usage")
+
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	os.Exit(cli{os.Stdin, os.Stdout, os.Stderr}.run(os.Args[1:]))
+}
+
+// run runs a command and returns the exit status: 0 on success, 1 on failure and 2 on wrong usage.
+func (cli cli) run(arguments []string) int {
+	if 0 == len(arguments) {
+		fmt.Fprint(cli.stderr, usage)
+		return 2
+	}
+	var fault error
+	switch command, arguments := arguments[0], arguments[1:]; command {
+	case "serve":
+		fault = cli.serve(arguments)
+	case "migrate":
+		fault = cli.migrate(arguments)
+	case "seed":
+		fault = cli.seed(arguments)
+	case "export":
+		fault = cli.export(arguments)
+	case "import":
+		fault = cli.importSubscribers(arguments)
+	case "check-config":
+		fault = cli.checkConfig(arguments)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(cli.stdout, usage)
+	default:
+		fmt.Fprintf(cli.stderr, "Unknown command %s.\n\n%s", command, usage)
+		return 2
+	}
+	switch {
+	case errors.Is(fault, flag.ErrHelp):
+		return 0
+	case errors.Is(fault, errUsage):
+		return 2
+	case fault != nil:
+		fmt.Fprintln(cli.stderr, "subscribers:", fault)
+		return 1
+	}
+	return 0
+}
+
+// flags makes the flag set of a command, with the --config flag that every command has.
+func (cli cli) flags(command string) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(cli.stderr)
+	configFile := flags.String("config", MarcGoRESTAPIDemo.DefaultConfigurationFile, "the configuration file")
+	return flags, configFile
+}
+
+// parse parses the flags of a command that takes no positional arguments and reads its configuration.
+func parse(flags *flag.FlagSet, configFile *string, arguments []string) (*MarcGoRESTAPIDemo.Configuration, error) {
+	if parseFail := flags.Parse(arguments); parseFail != nil {
+		if errors.Is(parseFail, flag.ErrHelp) {
+			return nil, parseFail
+		}
+		return nil, errUsage
+	}
+	if 0 != flags.NArg() {
+		fmt.Fprintf(flags.Output(), "Unexpected arguments %v.\n", flags.Args())
+		flags.Usage()
+		return nil, errUsage
+	}
+	return MarcGoRESTAPIDemo.ReadConfiguration(*configFile)
+}
+
+func (cli cli) serve(arguments []string) error {
+	flags, configFile := cli.flags("serve")
+	configuration, configurationFail := parse(flags, configFile, arguments)
+	if configurationFail != nil {
+		return configurationFail
+	}
+	if validateFail := configuration.Validate(); validateFail != nil {
+		return validateFail
+	}
+	// The database is migrated once the server listens, so that the server answers as not ready meanwhile.
+	autoMigrate := configuration.Database.AutoMigrate
+	configuration.Database.AutoMigrate = false
+	records, openFail := MarcGoRESTAPIDemo.OpenDatabaseRecords(configuration.Database)
+	if openFail != nil {
+		return openFail
+	}
+	server, serverFail := MarcGoRESTAPIDemo.MakeConfiguredServer(records, configuration)
+	if serverFail != nil {
+		records.Close()
+		return serverFail
+	}
+	if autoMigrate {
+		server.Prepare(records.MigrateUp)
+	}
+	fmt.Fprintln(cli.stdout, "Serving the subscribers API on", server.Addr())
+	return server.Run()
+}
+
+func (cli cli) migrate(arguments []string) error {
+	flags, configFile := cli.flags("migrate")
+	steps := flags.Uint("steps", 1, "the number of migrations that down rolls back")
+	var direction string
+	if 0 != len(arguments) {
+		direction, arguments = arguments[0], arguments[1:]
+	}
+	if "up" != direction && "down" != direction && "status" != direction {
+		fmt.Fprintf(cli.stderr, "Please migrate up, down or status.\n\n%s", usage)
+		return errUsage
+	}
+	configuration, configurationFail := parse(flags, configFile, arguments)
+	if configurationFail != nil {
+		return configurationFail
+	}
+	configuration.Database.AutoMigrate = false
+	records, openFail := MarcGoRESTAPIDemo.OpenDatabaseRecords(configuration.Database)
+	if openFail != nil {
+		return openFail
+	}
+	defer records.Close()
+	var migrateFail error
+	switch direction {
+	case "up":
+		migrateFail = records.MigrateUp()
+	case "down":
+		migrateFail = records.MigrateDown(*steps)
+	}
+	if migrateFail != nil {
+		return migrateFail
+	}
+	version, versionFail := records.SchemaVersion()
+	if versionFail != nil {
+		return versionFail
+	}
+	latest, latestFail := records.LatestSchemaVersion()
+	if latestFail != nil {
+		return latestFail
+	}
+	fmt.Fprintf(cli.stdout, "Schema version %d of %d.\n", version, latest)
+	return nil
+}
+
+// open opens the configured database for a command that works on subscribers.
+func (cli cli) open(command string, arguments []string,
+	define func(flags *flag.FlagSet)) (MarcGoRESTAPIDemo.Records, error) {
+	flags, configFile := cli.flags(command)
+	if define != nil {
+		define(flags)
+	}
+	configuration, configurationFail := parse(flags, configFile, arguments)
+	if configurationFail != nil {
+		return MarcGoRESTAPIDemo.Records{}, configurationFail
+	}
+	return MarcGoRESTAPIDemo.OpenDatabaseRecords(configuration.Database)
+}
+
+func (cli cli) seed(arguments []string) error {
+	records, openFail := cli.open("seed", arguments, nil)
+	if openFail != nil {
+		return openFail
+	}
+	defer records.Close()
+	_, createFail := cli.create(records, sampleSubscribers, false)
+	return createFail
+}
+
+// create creates the subscribers and reports each one that failed. It fails when any subscriber failed.
+func (cli cli) create(store MarcGoRESTAPIDemo.SubscriberStore, subscribers []MarcGoRESTAPIDemo.Subscriber,
+	atomic bool) (MarcGoRESTAPIDemo.BulkReport, error) {
+	report, createFail := MarcGoRESTAPIDemo.CreateSubscribers(context.Background(), store, subscribers, atomic)
+	if createFail != nil {
+		return report, createFail
+	}
+	for _, result := range report.Results {
+		if MarcGoRESTAPIDemo.BulkCreated != result.Status {
+			fmt.Fprintf(cli.stderr, "Subscriber %d %s %s. %s\n", result.Position+1, result.EmailAddress,
+				result.Status, result.Error)
+		}
+	}
+	fmt.Fprintf(cli.stdout, "Created %d of %d subscribers.\n", report.Created, len(subscribers))
+	if report.Created != len(subscribers) {
+		return report, fmt.Errorf("%d of %d subscribers were not created", len(subscribers)-report.Created,
+			len(subscribers))
+	}
+	return report, nil
+}
+
+func (cli cli) export(arguments []string) error {
+	var output *string
+	records, openFail := cli.open("export", arguments, func(flags *flag.FlagSet) {
+		output = flags.String("output", "", "the file to write, instead of standard output")
+	})
+	if openFail != nil {
+		return openFail
+	}
+	defer records.Close()
+	if "" == *output || "-" == *output {
+		return export(records, cli.stdout)
+	}
+	file, createFail := os.Create(*output)
+	if createFail != nil {
+		return createFail
+	}
+	if exportFail := export(records, file); exportFail != nil {
+		file.Close()
+		return exportFail
+	}
+	return file.Close()
+}
+
+// export writes the subscribers that are not deleted, one page at a time.
+func export(store MarcGoRESTAPIDemo.SubscriberStore, writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	var after uint64
+	for {
+		subscribers, listFail := store.List(context.Background(),
+			MarcGoRESTAPIDemo.ListQuery{Limit: exportPageSize, After: after})
+		if listFail != nil {
+			return listFail
+		}
+		for _, subscriber := range subscribers {
+			if encodeFail := encoder.Encode(subscriber); encodeFail != nil {
+				return encodeFail
+			}
+		}
+		if len(subscribers) < exportPageSize {
+			return nil
+		}
+		after = subscribers[len(subscribers)-1].Index
+	}
+}
+
+// importSubscribers creates new subscribers from their email addresses, names and activation flags. The indexes,
+// public IDs and timestamps of an export are given anew by the store. Subscribers are always created inactive, so the
+// ones that were active are activated once they are created.
+func (cli cli) importSubscribers(arguments []string) error {
+	var input *string
+	var atomic *bool
+	records, openFail := cli.open("import", arguments, func(flags *flag.FlagSet) {
+		input = flags.String("input", "", "the file to read, instead of standard input")
+		atomic = flags.Bool("atomic", false, "create no subscriber at all if any subscriber fails")
+	})
+	if openFail != nil {
+		return openFail
+	}
+	defer records.Close()
+	reader := cli.stdin
+	if "" != *input && "-" != *input {
+		file, openFail := os.Open(*input)
+		if openFail != nil {
+			return openFail
+		}
+		defer file.Close()
+		reader = file
+	}
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	var subscribers []MarcGoRESTAPIDemo.Subscriber
+	for decoder.More() {
+		var subscriber MarcGoRESTAPIDemo.Subscriber
+		if decodeFail := decoder.Decode(&subscriber); decodeFail != nil {
+			return fmt.Errorf("subscriber %d: %w", len(subscribers)+1, decodeFail)
+		}
+		subscribers = append(subscribers, MarcGoRESTAPIDemo.Subscriber{EmailAddress: subscriber.EmailAddress,
+			FirstName: subscriber.FirstName, LastName: subscriber.LastName, ActivationFlag: subscriber.ActivationFlag})
+	}
+	if 0 == len(subscribers) {
+		return errors.New("there are no subscribers to import")
+	}
+	after, lastFail := lastIndex(records)
+	if lastFail != nil {
+		return lastFail
+	}
+	report, createFail := cli.create(records, subscribers, *atomic)
+	if activateFail := activate(records, subscribers, report, after); activateFail != nil {
+		return activateFail
+	}
+	return createFail
+}
+
+// lastIndex returns the highest index of the subscribers that are not deleted, which every new subscriber is after.
+func lastIndex(store MarcGoRESTAPIDemo.SubscriberStore) (uint64, error) {
+	subscribers, listFail := store.List(context.Background(),
+		MarcGoRESTAPIDemo.ListQuery{Limit: 1, Sort: "index", Descending: true})
+	if listFail != nil || 0 == len(subscribers) {
+		return 0, listFail
+	}
+	return subscribers[0].Index, nil
+}
+
+// activate activates, in one transaction, the created subscribers that were active. They are found by their email
+// addresses, which are unique, among the subscribers after the last index before they were created.
+func activate(store MarcGoRESTAPIDemo.SubscriberStore, subscribers []MarcGoRESTAPIDemo.Subscriber,
+	report MarcGoRESTAPIDemo.BulkReport, after uint64) error {
+	activations := make(map[string]bool)
+	for _, result := range report.Results {
+		if MarcGoRESTAPIDemo.BulkCreated == result.Status && subscribers[result.Position].ActivationFlag {
+			activations[result.EmailAddress] = true
+		}
+	}
+	if 0 == len(activations) {
+		return nil
+	}
+	ctx := context.Background()
+	return store.Transaction(ctx, func(transaction MarcGoRESTAPIDemo.SubscriberStore) error {
+		for {
+			created, listFail := transaction.List(ctx, MarcGoRESTAPIDemo.ListQuery{Limit: exportPageSize, After: after})
+			if listFail != nil {
+				return listFail
+			}
+			for _, subscriber := range created {
+				if activations[subscriber.EmailAddress] {
+					if _, activateFail := transaction.Activate(ctx, subscriber.Index, true); activateFail != nil {
+						return activateFail
+					}
+				}
+			}
+			if len(created) < exportPageSize {
+				return nil
+			}
+			after = created[len(created)-1].Index
+		}
+	})
+}
+
+func (cli cli) checkConfig(arguments []string) error {
+	flags, configFile := cli.flags("check-config")
+	configuration, configurationFail := parse(flags, configFile, arguments)
+	if configurationFail != nil {
+		return configurationFail
+	}
+	if validateFail := configuration.Validate(); validateFail != nil {
+		return validateFail
+	}
+	fmt.Fprintf(cli.stdout, "Configuration %s is valid.\n", *configFile)
+	return nil
+}
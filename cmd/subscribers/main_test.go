@@ -0,0 +1,139 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marcanthonyconcepcion/MarcGoRESTAPIDemo"
+)
+
+// writeConfiguration writes the configuration of an SQLite database in a temporary directory.
+func writeConfiguration(t *testing.T, database string) string {
+	directory := t.TempDir()
+	fileName := filepath.Join(directory, "subscribers.yaml")
+	configuration := "database:\n  driver: " + database + "\n  dbname: " + filepath.Join(directory, "subscribers.db") +
+		"\nmvc:\n  pagesize: 20\n  maxpagesize: 100\n"
+	if writeFail := ioutil.WriteFile(fileName, []byte(configuration), 0600); writeFail != nil {
+		t.Fatal(writeFail)
+	}
+	return fileName
+}
+
+// activateSubscriber activates a subscriber of the configured database, which no command does.
+func activateSubscriber(t *testing.T, configFile string, index uint64) {
+	configuration, readFail := MarcGoRESTAPIDemo.ReadConfiguration(configFile)
+	if readFail != nil {
+		t.Fatal(readFail)
+	}
+	records, openFail := MarcGoRESTAPIDemo.OpenDatabaseRecords(configuration.Database)
+	if openFail != nil {
+		t.Fatal(openFail)
+	}
+	defer records.Close()
+	if _, activateFail := records.Activate(context.Background(), index, true); activateFail != nil {
+		t.Fatal(activateFail)
+	}
+}
+
+func runCommand(t *testing.T, input string, arguments ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := cli{strings.NewReader(input), &stdout, &stderr}.run(arguments)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestCommands(t *testing.T) {
+	configFile := writeConfiguration(t, "sqlite3")
+	for _, command := range []struct {
+		arguments      []string
+		input          string
+		expectedStatus int
+		expectedOutput string
+	}{
+		{[]string{"check-config", "--config", configFile}, "", 0, "Configuration " + configFile + " is valid.\n"},
+		{[]string{"migrate", "status", "--config", configFile}, "", 0, "Schema version 0 of 6.\n"},
+		{[]string{"migrate", "up", "--config", configFile}, "", 0, "Schema version 6 of 6.\n"},
+		{[]string{"migrate", "down", "--config", configFile, "--steps", "2"}, "", 0, "Schema version 4 of 6.\n"},
+		{[]string{"migrate", "up", "--config", configFile}, "", 0, "Schema version 6 of 6.\n"},
+		{[]string{"seed", "--config", configFile}, "", 0, "Created 3 of 3 subscribers.\n"},
+		{[]string{"seed", "--config", configFile}, "", 1, "Created 0 of 3 subscribers.\n"},
+		{[]string{"import", "--config", configFile}, `{"email_address": "riseofskywalker@starwars.com", "first_name": "Rey"}
+{"email_address": "kevin.andrews@email.com"}`, 1, "Created 1 of 2 subscribers.\n"},
+		{[]string{"import", "--config", configFile, "--atomic"}, `{"email_address": "poe@starwars.com"}
+{"email_address": "kevin.andrews@email.com"}`, 1, "Created 0 of 2 subscribers.\n"},
+		{[]string{"import", "--config", configFile}, `{"email": "poe@starwars.com"}`, 1, ""},
+		{[]string{"import", "--config", configFile}, "", 1, ""},
+		{[]string{"migrate", "sideways", "--config", configFile}, "", 2, ""},
+		{[]string{"seed", "--config", configFile, "extra"}, "", 2, ""},
+		{[]string{"seed", "--verbose"}, "", 2, ""},
+		{[]string{"seed", "--config", filepath.Join(t.TempDir(), "missing.yaml")}, "", 1, ""},
+		{[]string{"export", "--help"}, "", 0, ""},
+		{[]string{"publish"}, "", 2, ""},
+		{nil, "", 2, ""},
+	} {
+		status, output, errors := runCommand(t, command.input, command.arguments...)
+		if command.expectedStatus != status || command.expectedOutput != output {
+			t.Errorf("ERROR subscribers %v returned %d %q, expected %d %q. %s", command.arguments, status, output,
+				command.expectedStatus, command.expectedOutput, errors)
+		}
+	}
+
+	activateSubscriber(t, configFile, 1)
+	status, output, errors := runCommand(t, "", "export", "--config", configFile)
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if 0 != status || 4 != len(lines) {
+		t.Fatalf("ERROR exporting subscribers returned %d %s. %s", status, output, errors)
+	}
+	var subscriber MarcGoRESTAPIDemo.Subscriber
+	if jsonError := json.Unmarshal([]byte(lines[3]), &subscriber); jsonError != nil || 4 != subscriber.Index ||
+		"riseofskywalker@starwars.com" != subscriber.EmailAddress || "Rey" != subscriber.FirstName || "" == subscriber.PublicID {
+		t.Errorf("ERROR unexpected exported subscriber %s", lines[3])
+	}
+
+	copyFile := writeConfiguration(t, "sqlite3")
+	if status, _, errors = runCommand(t, "", "migrate", "up", "--config", copyFile); 0 != status {
+		t.Fatalf("ERROR migrating the copy. %s", errors)
+	}
+	if status, output, errors = runCommand(t, output, "import", "--config", copyFile); 0 != status ||
+		"Created 4 of 4 subscribers.\n" != output {
+		t.Errorf("ERROR importing the export returned %d %q. %s", status, output, errors)
+	}
+	exportFile := filepath.Join(t.TempDir(), "subscribers.ndjson")
+	if status, _, errors = runCommand(t, "", "export", "--config", copyFile, "--output", exportFile); 0 != status {
+		t.Fatalf("ERROR exporting the copy. %s", errors)
+	}
+	copied, readFail := ioutil.ReadFile(exportFile)
+	if readFail != nil {
+		t.Fatal(readFail)
+	}
+	for position, line := range strings.Split(strings.TrimSuffix(string(copied), "\n"), "\n") {
+		var copy MarcGoRESTAPIDemo.Subscriber
+		if jsonError := json.Unmarshal([]byte(line), &copy); jsonError != nil || (0 == position) != copy.ActivationFlag {
+			t.Errorf("ERROR unexpected activation of the copied subscriber %s", line)
+		}
+	}
+	if status, output, errors = runCommand(t, "", "import", "--config", copyFile, "--input", exportFile); 1 != status ||
+		"Created 0 of 4 subscribers.\n" != output {
+		t.Errorf("ERROR importing the export again returned %d %q. %s", status, output, errors)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	if status, _, errors := runCommand(t, "", "check-config", "--config", writeConfiguration(t, "oracle")); 1 != status ||
+		!strings.Contains(errors, "Database driver oracle is not supported.") {
+		t.Errorf("ERROR checking a configuration with an unsupported driver returned %d. %s", status, errors)
+	}
+	if status, _, _ := runCommand(t, "", "check-config", "--config", "../../"+MarcGoRESTAPIDemo.DefaultConfigurationFile); 0 != status {
+		t.Errorf("ERROR the default configuration is invalid.")
+	}
+}
@@ -7,35 +7,115 @@
 package MarcGoRESTAPIDemo
 
 import (
+	"errors"
+	"fmt"
 	"gopkg.in/yaml.v3"
 	"io/ioutil"
+	"sync"
+	"time"
 )
 
+type DatabaseConfiguration struct {
+	Driver       string
+	Host         string
+	Port         uint16
+	DBName       string
+	User         string
+	Password     string
+	SSLMode      string
+	AutoMigrate  bool
+	QueryTimeout time.Duration
+}
+
+type MVCConfiguration struct {
+	Resource       string
+	PageSize       int
+	MaxPageSize    int
+	RequireIfMatch bool
+	PurgeRetention time.Duration
+	PublicIDs      bool
+}
+
+// TLSConfiguration turns on TLS when CertFile is set. MinVersion is 1.2 (the default) or 1.3. With a ClientCAFile,
+// clients need a certificate that it signed, and with AllowedSubjects the certificate also needs one of the subjects.
+// The files are reloaded when they change, checked at most once per ReloadInterval (10 seconds by default).
+type TLSConfiguration struct {
+	CertFile        string
+	KeyFile         string
+	MinVersion      string
+	ClientCAFile    string
+	AllowedSubjects []string
+	ReloadInterval  time.Duration
+}
+
+// Zero timeouts do not time out. The address defaults to :8080, the shutdown timeout to 30 seconds and the readiness
+// timeout to 2 seconds. DrainDelay is how long the server keeps serving as not ready before it shuts down, 5 seconds by
+// default.
+type ServerConfiguration struct {
+	Address           string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	ReadinessTimeout  time.Duration
+	DrainDelay        time.Duration
+	TLS               TLSConfiguration
+}
+
 type Configuration struct {
-	Database struct {
-		Host     string
-		Port     uint16
-		DBName   string
-		User     string
-		Password string
-	}
-	MVC struct {
-		Resource string
-	}
-	Log struct {
+	Server   ServerConfiguration
+	Database DatabaseConfiguration
+	MVC      MVCConfiguration
+	Log      struct {
 		Filename string
 	}
 }
 
-func readConfiguration(fileName string) *Configuration {
+// DefaultConfigurationFile is read by the constructors that take no configuration, e.g. MakeDatabaseRecords.
+const DefaultConfigurationFile = "resources/MarcGoRESTAPIDemo.yaml"
+
+var (
+	defaultConfiguration     *Configuration
+	readDefaultConfiguration sync.Once
+)
+
+// settings reads the default configuration file the first time it is needed.
+func settings() *Configuration {
+	readDefaultConfiguration.Do(func() {
+		configuration, fault := ReadConfiguration(DefaultConfigurationFile)
+		if fault != nil {
+			panic(fault.Error())
+		}
+		defaultConfiguration = configuration
+	})
+	return defaultConfiguration
+}
+
+func ReadConfiguration(fileName string) (*Configuration, error) {
 	buffer, fault := ioutil.ReadFile(fileName)
 	if fault != nil {
-		panic(fault.Error())
+		return nil, fault
 	}
 	configuration := &Configuration{}
 	fault = yaml.Unmarshal(buffer, configuration)
 	if fault != nil {
-		panic(fault.Error())
+		return nil, fmt.Errorf("%s: %w", fileName, fault)
+	}
+	return configuration, nil
+}
+
+// Validate tells whether the API can be served as configured, without connecting to the database.
+func (configuration *Configuration) Validate() error {
+	if _, supported := dialects[configuration.Database.Driver]; !supported {
+		return errors.New("Database driver " + configuration.Database.Driver + " is not supported.")
+	}
+	if configuration.MVC.PageSize < 0 || configuration.MVC.MaxPageSize < 0 {
+		return errors.New("The mvc page sizes cannot be negative. Leave them out for the defaults.")
+	}
+	if 0 != configuration.MVC.MaxPageSize && configuration.MVC.MaxPageSize < configuration.MVC.PageSize {
+		return errors.New("The mvc page size must be from 0 to the max page size.")
 	}
-	return configuration
+	_, serverFail := makeServer(makeSubscriberController(MakeMemoryRecords(), configuration.MVC), configuration.Server)
+	return serverFail
 }
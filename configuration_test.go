@@ -7,16 +7,33 @@
 package MarcGoRESTAPIDemo
 
 import (
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestReadYamlFile(t *testing.T) {
-	configuration := readConfiguration("resources/MarcGoRESTAPIDemo.yaml")
-	defer func() {
-		if fault := recover(); fault != nil {
-			t.Errorf("Error reading configuration file %s.", fault)
-		}
-	}()
+	configuration, readFail := ReadConfiguration(DefaultConfigurationFile)
+	if readFail != nil {
+		t.Fatalf("Error reading configuration file %s.", readFail)
+	}
+	if ":8080" != configuration.Server.Address {
+		t.Errorf("Value %s is NOT the expected server address from the config file.", configuration.Server.Address)
+	}
+	if 5*time.Second != configuration.Server.ReadHeaderTimeout || 30*time.Second != configuration.Server.WriteTimeout {
+		t.Errorf("Values %s and %s are NOT the expected server timeouts from the config file.",
+			configuration.Server.ReadHeaderTimeout, configuration.Server.WriteTimeout)
+	}
+	if 2*time.Second != configuration.Server.ReadinessTimeout {
+		t.Errorf("Value %s is NOT the expected server readiness timeout from the config file.",
+			configuration.Server.ReadinessTimeout)
+	}
+	if 5*time.Second != configuration.Server.DrainDelay {
+		t.Errorf("Value %s is NOT the expected server drain delay from the config file.", configuration.Server.DrainDelay)
+	}
+	if "mysql" != configuration.Database.Driver {
+		t.Errorf("Value %s is NOT the expected database driver from the config file.", configuration.Database.Driver)
+	}
 	if "localhost" != configuration.Database.Host {
 		t.Errorf("Value %s is NOT the expected database host from the config file.", configuration.Database.Host)
 	}
@@ -32,7 +49,66 @@ func TestReadYamlFile(t *testing.T) {
 	if "password" != configuration.Database.Password {
 		t.Errorf("Value %s is NOT the expected database password from the config file.", configuration.Database.Password)
 	}
+	if !configuration.Database.AutoMigrate {
+		t.Errorf("Value %t is NOT the expected database automigrate from the config file.", configuration.Database.AutoMigrate)
+	}
+	if 5*time.Second != configuration.Database.QueryTimeout {
+		t.Errorf("Value %s is NOT the expected database query timeout from the config file.", configuration.Database.QueryTimeout)
+	}
 	if "subscribers" != configuration.MVC.Resource {
 		t.Errorf("Value %s is NOT the expected mvc resource from the config file.", configuration.MVC.Resource)
 	}
+	if 20 != configuration.MVC.PageSize {
+		t.Errorf("Value %d is NOT the expected mvc page size from the config file.", configuration.MVC.PageSize)
+	}
+	if 100 != configuration.MVC.MaxPageSize {
+		t.Errorf("Value %d is NOT the expected mvc max page size from the config file.", configuration.MVC.MaxPageSize)
+	}
+}
+
+func TestValidateConfiguration(t *testing.T) {
+	configuration, _ := ReadConfiguration(DefaultConfigurationFile)
+	if validateFail := configuration.Validate(); validateFail != nil {
+		t.Errorf("ERROR the default configuration is invalid. %s", validateFail.Error())
+	}
+	for name, invalidate := range map[string]func(configuration *Configuration){
+		"driver":        func(configuration *Configuration) { configuration.Database.Driver = "oracle" },
+		"page size":     func(configuration *Configuration) { configuration.MVC.MaxPageSize = 10 },
+		"tls":           func(configuration *Configuration) { configuration.Server.TLS.CertFile = "missing.pem" },
+		"subjects":      func(configuration *Configuration) { configuration.Server.TLS.AllowedSubjects = []string{"billing"} },
+		"negative":      func(configuration *Configuration) { configuration.MVC.PageSize = -1 },
+		"client ca":     func(configuration *Configuration) { configuration.Server.TLS.ClientCAFile = "ca.pem" },
+		"key file":      func(configuration *Configuration) { configuration.Server.TLS.CertFile = "server.pem" },
+		"tls version":   func(configuration *Configuration) { configuration.Server.TLS.MinVersion = "1.3" },
+		"max page size": func(configuration *Configuration) { configuration.MVC.MaxPageSize = -1 },
+	} {
+		invalid, _ := ReadConfiguration(DefaultConfigurationFile)
+		invalidate(invalid)
+		if nil == invalid.Validate() {
+			t.Errorf("ERROR a configuration with an invalid %s was accepted.", name)
+		}
+	}
+	for _, mvc := range []MVCConfiguration{{}, {PageSize: 50}, {MaxPageSize: 10}} {
+		defaulted, _ := ReadConfiguration(DefaultConfigurationFile)
+		defaulted.MVC = mvc
+		if validateFail := defaulted.Validate(); validateFail != nil {
+			t.Errorf("ERROR a configuration that leaves page sizes to the defaults was refused. %s", validateFail.Error())
+		}
+	}
+	for setting, tlsConfiguration := range map[string]TLSConfiguration{
+		"certfile": {ClientCAFile: "ca.pem"},
+		"keyfile":  {CertFile: "server.pem"},
+	} {
+		invalid, _ := ReadConfiguration(DefaultConfigurationFile)
+		invalid.Server.TLS = tlsConfiguration
+		if validateFail := invalid.Validate(); nil == validateFail || !strings.Contains(validateFail.Error(), setting) {
+			t.Errorf("ERROR a TLS configuration without a %s was not refused for it. %v", setting, validateFail)
+		}
+	}
+	if _, readFail := ReadConfiguration("resources/missing.yaml"); readFail == nil {
+		t.Errorf("ERROR reading a missing configuration file did not fail.")
+	}
+	if _, openFail := OpenDatabaseRecords(DatabaseConfiguration{Driver: "oracle"}); openFail == nil {
+		t.Errorf("ERROR opening a database of an unsupported driver did not fail.")
+	}
 }
@@ -5,6 +5,8 @@ go 1.16
 require (
 	github.com/go-sql-driver/mysql v1.6.0
 	github.com/gorilla/mux v1.8.0
+	github.com/lib/pq v1.10.9
 	github.com/marcanthonyconcepcion/MarcPHPRESTAPIDemo v0.0.0-20210409073951-4ca70410ac8e // indirect
+	github.com/mattn/go-sqlite3 v1.14.16
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
@@ -0,0 +1,220 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed resources/migrations
+var migrationFiles embed.FS
+
+const createSchemaMigrationsTable = "create table if not exists `schema_migrations` (" +
+	"`version` bigint primary key not null, " +
+	"`name` varchar(255) not null)"
+
+type migration struct {
+	version uint
+	name    string
+	up      string
+	down    string
+}
+
+// Migrations are read from resources/migrations/<driver>/<version>_<name>.<up|down>.sql.
+func loadMigrations(driver string) ([]migration, error) {
+	directory := path.Join("resources/migrations", driver)
+	entries, readFail := fs.ReadDir(migrationFiles, directory)
+	if readFail != nil {
+		return nil, readFail
+	}
+	migrationsByVersion := make(map[uint]*migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+		separator := strings.IndexByte(name, '_')
+		if separator < 0 {
+			return nil, errors.New("Migration file " + name + " is not named <version>_<name>.<up|down>.sql.")
+		}
+		version, versionFail := strconv.ParseUint(name[:separator], 10, 32)
+		if versionFail != nil || 0 == version {
+			return nil, errors.New("Migration file " + name + " does not start with a positive version number.")
+		}
+		contents, readFail := fs.ReadFile(migrationFiles, path.Join(directory, name))
+		if readFail != nil {
+			return nil, readFail
+		}
+		current, found := migrationsByVersion[uint(version)]
+		if !found {
+			current = &migration{version: uint(version), name: strings.TrimSuffix(name[separator+1:], "."+direction+".sql")}
+			migrationsByVersion[uint(version)] = current
+		}
+		if "up" == direction {
+			current.up = string(contents)
+		} else {
+			current.down = string(contents)
+		}
+	}
+	migrations := make([]migration, 0, len(migrationsByVersion))
+	for _, current := range migrationsByVersion {
+		if "" == current.up || "" == current.down {
+			return nil, errors.New("Migration " + strconv.Itoa(int(current.version)) + " needs both an up and a down file.")
+		}
+		migrations = append(migrations, *current)
+	}
+	sort.Slice(migrations, func(left, right int) bool {
+		return migrations[left].version < migrations[right].version
+	})
+	return migrations, nil
+}
+
+func splitStatements(script string) []string {
+	var statements []string
+	for _, statement := range strings.Split(script, ";\n") {
+		if statement = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(statement), ";")); "" != statement {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+func (records Records) SchemaVersion() (uint, error) {
+	if _, createFail := records.exec(context.Background(), createSchemaMigrationsTable); createFail != nil {
+		return 0, createFail
+	}
+	var version uint
+	versionFail := records.queryRow(context.Background(), "select coalesce(max(`version`), 0) from `schema_migrations`").Scan(&version)
+	return version, versionFail
+}
+
+// CheckSchema tells whether the database is at the latest schema version, without creating the schema_migrations table.
+func (records Records) CheckSchema(ctx context.Context) error {
+	ctx, cancel := records.withTimeout(ctx)
+	defer cancel()
+	var version uint
+	versionFail := records.queryRow(ctx, "select coalesce(max(`version`), 0) from `schema_migrations`").Scan(&version)
+	if versionFail != nil {
+		return storeFault(ctx, versionFail)
+	}
+	latest, latestFail := records.LatestSchemaVersion()
+	if latestFail != nil {
+		return latestFail
+	}
+	if version != latest {
+		return errors.New("Schema version " + strconv.Itoa(int(version)) + " is not the latest version " +
+			strconv.Itoa(int(latest)) + ".")
+	}
+	return nil
+}
+
+func (records Records) LatestSchemaVersion() (uint, error) {
+	migrations, loadFail := loadMigrations(records.dialect.driver())
+	if loadFail != nil || 0 == len(migrations) {
+		return 0, loadFail
+	}
+	return migrations[len(migrations)-1].version, nil
+}
+
+func (records Records) MigrateUp() error {
+	latest, latestFail := records.LatestSchemaVersion()
+	if latestFail != nil {
+		return latestFail
+	}
+	return records.MigrateTo(latest)
+}
+
+func (records Records) MigrateDown(steps uint) error {
+	current, currentFail := records.SchemaVersion()
+	if currentFail != nil {
+		return currentFail
+	}
+	migrations, loadFail := loadMigrations(records.dialect.driver())
+	if loadFail != nil {
+		return loadFail
+	}
+	target := uint(0)
+	for position := len(migrations) - 1; position >= 0; position-- {
+		if migrations[position].version > current {
+			continue
+		}
+		if 0 == steps {
+			target = migrations[position].version
+			break
+		}
+		steps--
+	}
+	return records.MigrateTo(target)
+}
+
+func (records Records) MigrateTo(version uint) error {
+	current, currentFail := records.SchemaVersion()
+	if currentFail != nil {
+		return currentFail
+	}
+	migrations, loadFail := loadMigrations(records.dialect.driver())
+	if loadFail != nil {
+		return loadFail
+	}
+	if 0 != version && (0 == len(migrations) || version > migrations[len(migrations)-1].version) {
+		return errors.New("Schema version " + strconv.Itoa(int(version)) + " does not exist.")
+	}
+	if version >= current {
+		for _, pending := range migrations {
+			if pending.version > current && pending.version <= version {
+				if migrateFail := records.applyMigration(pending.up,
+					"insert into `schema_migrations` (`version`, `name`) values (?, ?)",
+					pending.version, pending.name); migrateFail != nil {
+					return errors.New("Migration " + strconv.Itoa(int(pending.version)) + " up failed. " + migrateFail.Error())
+				}
+			}
+		}
+		return nil
+	}
+	for position := len(migrations) - 1; position >= 0; position-- {
+		applied := migrations[position]
+		if applied.version <= current && applied.version > version {
+			if migrateFail := records.applyMigration(applied.down,
+				"delete from `schema_migrations` where `version` = ?", applied.version); migrateFail != nil {
+				return errors.New("Migration " + strconv.Itoa(int(applied.version)) + " down failed. " + migrateFail.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// MySQL commits DDL implicitly, so a failing statement there can leave a migration partially applied.
+func (records Records) applyMigration(script string, bookkeeping string, arguments ...interface{}) error {
+	transaction, beginFail := records.database.Begin()
+	if beginFail != nil {
+		return beginFail
+	}
+	for _, statement := range splitStatements(script) {
+		if _, statementFail := transaction.Exec(statement); statementFail != nil {
+			_ = transaction.Rollback()
+			return statementFail
+		}
+	}
+	if _, bookkeepingFail := transaction.Exec(render(records.dialect, bookkeeping), arguments...); bookkeepingFail != nil {
+		_ = transaction.Rollback()
+		return bookkeepingFail
+	}
+	return transaction.Commit()
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrationsExistForEveryDriver(t *testing.T) {
+	expectedMigrations, loadFail := loadMigrations("mysql")
+	if loadFail != nil {
+		t.Fatalf("ERROR loading mysql migrations. %s", loadFail.Error())
+	}
+	for _, driver := range []string{"postgres", "sqlite3"} {
+		migrations, loadFail := loadMigrations(driver)
+		if loadFail != nil {
+			t.Fatalf("ERROR loading %s migrations. %s", driver, loadFail.Error())
+		}
+		if len(expectedMigrations) != len(migrations) {
+			t.Fatalf("ERROR %s has %d migrations, mysql has %d.", driver, len(migrations), len(expectedMigrations))
+		}
+		for position, expectedMigration := range expectedMigrations {
+			if expectedMigration.version != migrations[position].version || expectedMigration.name != migrations[position].name {
+				t.Errorf("ERROR %s migration %d_%s does not match mysql migration %d_%s.", driver,
+					migrations[position].version, migrations[position].name, expectedMigration.version, expectedMigration.name)
+			}
+		}
+	}
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	dut := makeDatabaseRecords(DatabaseConfiguration{
+		Driver: "sqlite3",
+		DBName: filepath.Join(t.TempDir(), "subscribers_database.db"),
+	})
+	defer dut.database.Close()
+	latest, latestFail := dut.LatestSchemaVersion()
+	if latestFail != nil || 0 == latest {
+		t.Fatalf("ERROR reading the latest schema version %d. %v", latest, latestFail)
+	}
+	if migrateFail := dut.MigrateUp(); migrateFail != nil {
+		t.Fatalf("ERROR migrating up. %s", migrateFail.Error())
+	}
+	if version, _ := dut.SchemaVersion(); latest != version {
+		t.Errorf("ERROR schema version is %d after migrating up, expected %d.", version, latest)
+	}
+	if migrateFail := dut.MigrateUp(); migrateFail != nil {
+		t.Errorf("ERROR migrating up twice. %s", migrateFail.Error())
+	}
+	if _, createFail := dut.Create(context.Background(), Subscriber{EmailAddress: "riseofskywalker@starwars.com"}); createFail != nil {
+		t.Errorf("ERROR creating a record after migrating up. %s", createFail.Error())
+	}
+	if migrateFail := dut.MigrateDown(latest); migrateFail != nil {
+		t.Fatalf("ERROR migrating down. %s", migrateFail.Error())
+	}
+	if version, _ := dut.SchemaVersion(); 0 != version {
+		t.Errorf("ERROR schema version is %d after migrating down, expected 0.", version)
+	}
+	if _, listFail := dut.List(context.Background(), ListQuery{}); listFail == nil {
+		t.Errorf("ERROR subscribers table still exists after migrating down.")
+	}
+	if migrateFail := dut.MigrateTo(latest + 1); migrateFail == nil {
+		t.Errorf("ERROR migrating to a schema version that does not exist succeeded.")
+	}
+}
+
+func TestStatusMigrationKeepsActivatedSubscribers(t *testing.T) {
+	dut := makeDatabaseRecords(DatabaseConfiguration{
+		Driver: "sqlite3",
+		DBName: filepath.Join(t.TempDir(), "subscribers_database.db"),
+	})
+	defer dut.database.Close()
+	if migrateFail := dut.MigrateTo(1); migrateFail != nil {
+		t.Fatalf("ERROR migrating to version 1. %s", migrateFail.Error())
+	}
+	if _, insertFail := dut.exec(context.Background(), "insert into `subscribers` (`email_address`, `activation_flag`) "+
+		"values ('riseofskywalker@starwars.com', 1), ('kevin.andrews@email.com', 0)"); insertFail != nil {
+		t.Fatalf("ERROR creating records before the status migration. %s", insertFail.Error())
+	}
+	if migrateFail := dut.MigrateUp(); migrateFail != nil {
+		t.Fatalf("ERROR migrating up. %s", migrateFail.Error())
+	}
+	for index, expectedStatus := range map[uint64]string{1: SubscriberActive, 2: SubscriberPending} {
+		if lifecycle, lifecycleFail := dut.Lifecycle(context.Background(), index); lifecycleFail != nil ||
+			expectedStatus != lifecycle.Status {
+			t.Errorf("ERROR subscriber #%d has status %s, expected %s. %v", index, lifecycle.Status, expectedStatus,
+				lifecycleFail)
+		}
+	}
+}
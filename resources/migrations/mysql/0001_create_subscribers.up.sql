@@ -0,0 +1,7 @@
+create table if not exists `subscribers` (
+	`index`				int				primary key auto_increment,
+    `email_address`		varchar(255)	not null unique,
+    `last_name`			varchar(50),
+    `first_name`		varchar(100),
+    `activation_flag`	tinyint			default 0 not null
+);
@@ -0,0 +1,10 @@
+alter table `subscribers` add column `status` varchar(16) default 'pending' not null;
+update `subscribers` set `status` = 'active' where `activation_flag` = 1;
+create table if not exists `subscriber_transitions` (
+	`id`				bigint			primary key auto_increment,
+    `index`				int				not null,
+    `from_status`		varchar(16)		not null,
+    `to_status`			varchar(16)		not null,
+    `transitioned_at`	datetime(6)		not null,
+    foreign key (`index`) references `subscribers` (`index`) on delete cascade
+);
@@ -0,0 +1 @@
+alter table `subscribers` add column `version` bigint unsigned default 1 not null;
@@ -0,0 +1,3 @@
+alter table `subscribers`
+    add column `created_at` datetime(6) default current_timestamp(6) not null,
+    add column `updated_at` datetime(6) default current_timestamp(6) not null;
@@ -0,0 +1 @@
+alter table `subscribers` add column `deleted_at` datetime(6) null;
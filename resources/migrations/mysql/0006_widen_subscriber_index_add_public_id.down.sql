@@ -0,0 +1,5 @@
+alter table `subscribers` drop column `public_id`;
+set foreign_key_checks = 0;
+alter table `subscriber_transitions` modify `index` int not null;
+alter table `subscribers` modify `index` int auto_increment;
+set foreign_key_checks = 1;
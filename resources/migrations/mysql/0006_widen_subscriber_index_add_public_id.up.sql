@@ -0,0 +1,7 @@
+set foreign_key_checks = 0;
+alter table `subscribers` modify `index` bigint auto_increment;
+alter table `subscriber_transitions` modify `index` bigint not null;
+set foreign_key_checks = 1;
+alter table `subscribers` add column `public_id` char(36) null;
+update `subscribers` set `public_id` = uuid();
+alter table `subscribers` modify `public_id` char(36) not null, add unique (`public_id`);
@@ -0,0 +1,7 @@
+create table if not exists "subscribers" (
+	"index"				serial			primary key,
+    "email_address"		varchar(255)	not null unique,
+    "last_name"			varchar(50),
+    "first_name"		varchar(100),
+    "activation_flag"	smallint		default 0 not null
+);
@@ -0,0 +1 @@
+alter table "subscribers" drop column "updated_at", drop column "created_at";
@@ -0,0 +1,3 @@
+alter table "subscribers"
+    add column "created_at" timestamptz default current_timestamp not null,
+    add column "updated_at" timestamptz default current_timestamp not null;
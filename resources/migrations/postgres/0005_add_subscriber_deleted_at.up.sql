@@ -0,0 +1 @@
+alter table "subscribers" add column "deleted_at" timestamptz null;
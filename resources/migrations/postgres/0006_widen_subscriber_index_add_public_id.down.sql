@@ -0,0 +1,4 @@
+alter table "subscribers" drop column "public_id";
+alter table "subscriber_transitions" alter column "index" type integer;
+alter sequence "subscribers_index_seq" as integer;
+alter table "subscribers" alter column "index" type integer;
@@ -0,0 +1,4 @@
+alter table "subscribers" alter column "index" type bigint;
+alter sequence "subscribers_index_seq" as bigint;
+alter table "subscriber_transitions" alter column "index" type bigint;
+alter table "subscribers" add column "public_id" uuid default gen_random_uuid() not null unique;
@@ -0,0 +1,2 @@
+drop table if exists "subscriber_transitions";
+alter table "subscribers" drop column "status";
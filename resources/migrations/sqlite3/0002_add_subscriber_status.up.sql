@@ -0,0 +1,9 @@
+alter table "subscribers" add column "status" varchar(16) default 'pending' not null;
+update "subscribers" set "status" = 'active' where "activation_flag" = 1;
+create table if not exists "subscriber_transitions" (
+	"id"				integer			primary key autoincrement,
+    "index"				integer			not null references "subscribers" ("index") on delete cascade,
+    "from_status"		varchar(16)		not null,
+    "to_status"			varchar(16)		not null,
+    "transitioned_at"	timestamp		not null
+);
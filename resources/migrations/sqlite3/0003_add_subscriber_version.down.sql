@@ -0,0 +1 @@
+alter table "subscribers" drop column "version";
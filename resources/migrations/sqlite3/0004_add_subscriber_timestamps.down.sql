@@ -0,0 +1,2 @@
+alter table "subscribers" drop column "updated_at";
+alter table "subscribers" drop column "created_at";
@@ -0,0 +1,4 @@
+alter table "subscribers" add column "created_at" timestamp;
+alter table "subscribers" add column "updated_at" timestamp;
+update "subscribers" set "created_at" = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'),
+    "updated_at" = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now');
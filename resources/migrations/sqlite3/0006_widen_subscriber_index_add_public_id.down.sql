@@ -0,0 +1,2 @@
+drop index "subscribers_public_id";
+alter table "subscribers" drop column "public_id";
@@ -0,0 +1,5 @@
+alter table "subscribers" add column "public_id" varchar(36);
+update "subscribers" set "public_id" = lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' ||
+    substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) ||
+    substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)));
+create unique index "subscribers_public_id" on "subscribers" ("public_id");
@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultAddress         = ":8080"
+	defaultShutdownTimeout = 30 * time.Second
+	defaultDrainDelay      = 5 * time.Second
+)
+
+// Server serves the subscribers API of a store, with the health probes /healthz and /readyz. It is not ready until it
+// listens and has prepared its store, nor once it drains. Shutting it down drains the requests in flight and then
+// closes the store, when the store can be closed.
+type Server struct {
+	serverState      int32
+	prepare          func() error
+	server           *http.Server
+	handler          http.Handler
+	store            SubscriberStore
+	shutdownTimeout  time.Duration
+	readinessTimeout time.Duration
+	drainDelay       time.Duration
+}
+
+// MakeServer serves the store as configured in DefaultConfigurationFile.
+func MakeServer(store SubscriberStore) (*Server, error) {
+	return makeServer(MakeSubscriberController(store), settings().Server)
+}
+
+func MakeConfiguredServer(store SubscriberStore, configuration *Configuration) (*Server, error) {
+	return makeServer(makeSubscriberController(store, configuration.MVC), configuration.Server)
+}
+
+func makeServer(controller SubscriberController, configuration ServerConfiguration) (*Server, error) {
+	address := configuration.Address
+	if "" == address {
+		address = defaultAddress
+	}
+	shutdownTimeout := configuration.ShutdownTimeout
+	if 0 == shutdownTimeout {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+	readinessTimeout := configuration.ReadinessTimeout
+	if 0 == readinessTimeout {
+		readinessTimeout = defaultReadinessTimeout
+	}
+	drainDelay := configuration.DrainDelay
+	if 0 == drainDelay {
+		drainDelay = defaultDrainDelay
+	}
+	handler := controller.Handler()
+	if tlsFail := checkTLSConfiguration(configuration.TLS); tlsFail != nil {
+		return nil, tlsFail
+	}
+	var tlsConfig *tls.Config
+	if "" != configuration.TLS.CertFile {
+		var tlsFail error
+		if tlsConfig, tlsFail = makeTLSConfig(configuration.TLS); tlsFail != nil {
+			return nil, tlsFail
+		}
+	}
+	if 0 != len(configuration.TLS.AllowedSubjects) {
+		if nil == tlsConfig || "" == configuration.TLS.ClientCAFile {
+			return nil, errors.New("Allowed subjects need TLS with a client CA file.")
+		}
+		handler = controller.authorizing(handler, configuration.TLS.AllowedSubjects)
+	}
+	server := &Server{
+		server: &http.Server{
+			Addr:              address,
+			TLSConfig:         tlsConfig,
+			ReadTimeout:       configuration.ReadTimeout,
+			ReadHeaderTimeout: configuration.ReadHeaderTimeout,
+			WriteTimeout:      configuration.WriteTimeout,
+			IdleTimeout:       configuration.IdleTimeout,
+		},
+		handler:          handler,
+		store:            controller.model,
+		shutdownTimeout:  shutdownTimeout,
+		readinessTimeout: readinessTimeout,
+		drainDelay:       drainDelay,
+	}
+	server.server.Handler = server.routing(controller, handler)
+	return server, nil
+}
+
+// Handler routes the requests of the API, so that it can be mounted in another server. The health probes are left to
+// the server that mounts it.
+func (server *Server) Handler() http.Handler {
+	return server.handler
+}
+
+func (server *Server) Addr() string {
+	return server.server.Addr
+}
+
+// Prepare sets the work that the server does once it listens and before it is ready, e.g. migrating its database. The
+// server stops serving when the work fails.
+func (server *Server) Prepare(prepare func() error) {
+	server.prepare = prepare
+}
+
+// ListenAndServe serves on the configured address until the server is shut down, which is not an error. It speaks
+// HTTPS when TLS is configured.
+func (server *Server) ListenAndServe() error {
+	listener, listenFail := net.Listen("tcp", server.server.Addr)
+	if listenFail != nil {
+		return listenFail
+	}
+	return server.Serve(listener)
+}
+
+// Serve serves on a listener until the server is shut down, which is not an error. It speaks HTTPS when TLS is
+// configured. The server is ready once it is prepared; it fails with the preparation when the preparation fails.
+func (server *Server) Serve(listener net.Listener) error {
+	prepareFail := make(chan error, 1)
+	if nil == server.prepare {
+		prepareFail <- nil
+		server.enter(serverServing)
+	} else {
+		go func() {
+			fault := server.prepare()
+			prepareFail <- fault
+			if fault != nil {
+				server.server.Close()
+				return
+			}
+			server.enter(serverServing)
+		}()
+	}
+	var serveFail error
+	if server.server.TLSConfig != nil {
+		serveFail = served(server.server.ServeTLS(listener, "", ""))
+	} else {
+		serveFail = served(server.server.Serve(listener))
+	}
+	select {
+	case fault := <-prepareFail:
+		if fault != nil {
+			return fault
+		}
+	default:
+	}
+	return serveFail
+}
+
+// Shutdown stops accepting requests, waits for the requests in flight until ctx is done and then closes the store.
+func (server *Server) Shutdown(ctx context.Context) error {
+	server.enter(serverDraining)
+	shutdownFail := server.server.Shutdown(ctx)
+	if closer, isCloser := server.store.(io.Closer); isCloser {
+		if closeFail := closer.Close(); shutdownFail == nil {
+			shutdownFail = closeFail
+		}
+	}
+	return shutdownFail
+}
+
+// Run serves on the configured address until SIGINT or SIGTERM, then drains: the server answers as not ready for the
+// drain delay, so that load balancers stop sending it requests, and shuts down within the shutdown timeout.
+func (server *Server) Run() error {
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	serveFail := make(chan error, 1)
+	go func() {
+		serveFail <- server.ListenAndServe()
+	}()
+	select {
+	case fault := <-serveFail:
+		return fault
+	case <-signals.Done():
+	}
+	stop()
+	return server.drain()
+}
+
+func (server *Server) drain() error {
+	server.enter(serverDraining)
+	time.Sleep(server.drainDelay)
+	ctx, cancel := context.WithTimeout(context.Background(), server.shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+func served(fault error) error {
+	if errors.Is(fault, http.ErrServerClosed) {
+		return nil
+	}
+	return fault
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const defaultReadinessTimeout = 2 * time.Second
+
+const (
+	HealthPass = "pass"
+	HealthFail = "fail"
+)
+
+// The states of a server, in the order that it goes through them.
+const (
+	serverStarting int32 = iota
+	serverServing
+	serverDraining
+)
+
+var serverStates = map[int32]string{serverStarting: "starting", serverServing: "serving", serverDraining: "draining"}
+
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Health passes when all of its checks pass.
+type Health struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+// readinessChecker is implemented by stores that can be unavailable, i.e. the database stores.
+type readinessChecker interface {
+	Ping(ctx context.Context) error
+	CheckSchema(ctx context.Context) error
+}
+
+// routing answers the health probes of the server and passes every other request to the API.
+func (server *Server) routing(controller SubscriberController, api http.Handler) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		var probe func(ctx context.Context) Health
+		switch request.URL.Path {
+		case "/healthz":
+			probe = server.liveness
+		case "/readyz":
+			probe = server.readiness
+		default:
+			api.ServeHTTP(response, request)
+			return
+		}
+		if http.MethodGet != request.Method && http.MethodHead != request.Method {
+			response.Header().Set("Allow", "GET, HEAD")
+			controller.sendErrorMessage(response, request, http.StatusMethodNotAllowed,
+				"Please probe the health of the server with GET or HEAD.")
+			return
+		}
+		health := probe(request.Context())
+		status := http.StatusOK
+		if HealthPass != health.Status {
+			status = http.StatusServiceUnavailable
+		}
+		response.Header().Set("Cache-Control", "no-store")
+		controller.send(response, request, status, health)
+	})
+}
+
+// liveness passes as long as the process can answer, whatever the state of the server and its dependencies.
+func (server *Server) liveness(context.Context) Health {
+	return makeHealth([]HealthCheck{{Name: "server", Status: HealthPass, Detail: server.state()}})
+}
+
+// readiness passes when the server is serving rather than starting or draining, and the store is reachable and
+// migrated within the readiness timeout.
+func (server *Server) readiness(ctx context.Context) Health {
+	state := server.state()
+	checks := []HealthCheck{{Name: "server", Status: HealthPass, Detail: state}}
+	if serverStates[serverServing] != state {
+		checks[0].Status = HealthFail
+	}
+	if checker, isChecker := server.store.(readinessChecker); isChecker {
+		ctx, cancel := context.WithTimeout(ctx, server.readinessTimeout)
+		defer cancel()
+		checks = append(checks, check("database", "The database is unreachable.", checker.Ping(ctx)),
+			check("schema", "The database schema is not migrated to the latest version.", checker.CheckSchema(ctx)))
+	}
+	return makeHealth(checks)
+}
+
+// check fails with a fixed detail, since the probes are unauthenticated, and logs the cause of the failure.
+func check(name string, detail string, fault error) HealthCheck {
+	if fault != nil {
+		log.Printf("Failed the %s readiness check: %v", name, fault)
+		return HealthCheck{Name: name, Status: HealthFail, Detail: detail}
+	}
+	return HealthCheck{Name: name, Status: HealthPass}
+}
+
+func makeHealth(checks []HealthCheck) Health {
+	health := Health{Status: HealthPass, Checks: checks}
+	for _, check := range checks {
+		if HealthPass != check.Status {
+			health.Status = HealthFail
+		}
+	}
+	return health
+}
+
+func (server *Server) state() string {
+	return serverStates[atomic.LoadInt32(&server.serverState)]
+}
+
+// enter moves the server to a later state. A draining server never serves again.
+func (server *Server) enter(state int32) {
+	for current := atomic.LoadInt32(&server.serverState); current < state; current = atomic.LoadInt32(&server.serverState) {
+		if atomic.CompareAndSwapInt32(&server.serverState, current, state) {
+			return
+		}
+	}
+}
@@ -0,0 +1,241 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// unreadyStore reports the readiness that a test sets.
+type unreadyStore struct {
+	*MemoryRecords
+	pingFail   error
+	schemaFail error
+}
+
+func (store *unreadyStore) Ping(context.Context) error {
+	return store.pingFail
+}
+
+func (store *unreadyStore) CheckSchema(context.Context) error {
+	return store.schemaFail
+}
+
+func probe(t *testing.T, server *Server, method string, target string) (int, Health) {
+	response := httptest.NewRecorder()
+	server.server.Handler.ServeHTTP(response, httptest.NewRequest(method, target, nil))
+	var health Health
+	if http.StatusMethodNotAllowed != response.Code {
+		if jsonError := json.Unmarshal(response.Body.Bytes(), &health); jsonError != nil {
+			t.Fatalf("ERROR %s %s returned %s", method, target, response.Body.String())
+		}
+		if "no-store" != response.Header().Get("Cache-Control") {
+			t.Errorf("ERROR %s %s may be cached.", method, target)
+		}
+	}
+	return response.Code, health
+}
+
+func TestServerHealth(t *testing.T) {
+	store := &unreadyStore{MemoryRecords: MakeMemoryRecords()}
+	server, _ := makeServer(makeSubscriberController(store, MVCConfiguration{PageSize: 10, MaxPageSize: 20}),
+		ServerConfiguration{})
+	schemaFail := errors.New("Schema version 5 is not the latest version 6.")
+	for _, step := range []struct {
+		name           string
+		change         func()
+		target         string
+		expectedStatus int
+		expected       Health
+	}{
+		{"starting", func() {}, "/healthz", http.StatusOK,
+			Health{HealthPass, []HealthCheck{{"server", HealthPass, "starting"}}}},
+		{"starting", func() {}, "/readyz", http.StatusServiceUnavailable,
+			Health{HealthFail, []HealthCheck{{"server", HealthFail, "starting"}, {"database", HealthPass, ""},
+				{"schema", HealthPass, ""}}}},
+		{"serving", func() { server.enter(serverServing) }, "/readyz", http.StatusOK,
+			Health{HealthPass, []HealthCheck{{"server", HealthPass, "serving"}, {"database", HealthPass, ""},
+				{"schema", HealthPass, ""}}}},
+		{"unreachable", func() { store.pingFail = errors.New("connection refused") }, "/readyz", http.StatusServiceUnavailable,
+			Health{HealthFail, []HealthCheck{{"server", HealthPass, "serving"},
+				{"database", HealthFail, "The database is unreachable."}, {"schema", HealthPass, ""}}}},
+		{"unreachable", func() {}, "/healthz", http.StatusOK,
+			Health{HealthPass, []HealthCheck{{"server", HealthPass, "serving"}}}},
+		{"unmigrated", func() { store.pingFail, store.schemaFail = nil, schemaFail }, "/readyz", http.StatusServiceUnavailable,
+			Health{HealthFail, []HealthCheck{{"server", HealthPass, "serving"}, {"database", HealthPass, ""},
+				{"schema", HealthFail, "The database schema is not migrated to the latest version."}}}},
+		{"draining", func() { store.schemaFail = nil; server.Shutdown(context.Background()) }, "/readyz",
+			http.StatusServiceUnavailable, Health{HealthFail, []HealthCheck{{"server", HealthFail, "draining"},
+				{"database", HealthPass, ""}, {"schema", HealthPass, ""}}}},
+		{"draining", func() { server.enter(serverServing) }, "/healthz", http.StatusOK,
+			Health{HealthPass, []HealthCheck{{"server", HealthPass, "draining"}}}},
+	} {
+		step.change()
+		status, health := probe(t, server, "GET", step.target)
+		if step.expectedStatus != status || ConvertToJson(step.expected) != ConvertToJson(health) {
+			t.Errorf("ERROR %s %s returned %v %s, expected %v %s", step.name, step.target, status, ConvertToJson(health),
+				step.expectedStatus, ConvertToJson(step.expected))
+		}
+	}
+	if status, _ := probe(t, server, "HEAD", "/healthz"); http.StatusOK != status {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	if status, _ := probe(t, server, "POST", "/readyz"); http.StatusMethodNotAllowed != status {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusMethodNotAllowed)
+	}
+
+	memoryServer, _ := makeServer(makeSubscriberController(MakeMemoryRecords(), MVCConfiguration{}), ServerConfiguration{})
+	memoryServer.enter(serverServing)
+	if status, health := probe(t, memoryServer, "GET", "/readyz"); http.StatusOK != status || 1 != len(health.Checks) {
+		t.Errorf("ERROR a memory store was not ready: %v %s", status, ConvertToJson(health))
+	}
+	response := httptest.NewRecorder()
+	memoryServer.Handler().ServeHTTP(response, httptest.NewRequest("GET", "/readyz", nil))
+	if http.StatusNotFound != response.Code {
+		t.Errorf("ERROR the API handler answered a health probe: %v", response.Code)
+	}
+}
+
+func TestRecordsReadiness(t *testing.T) {
+	dut := makeDatabaseRecords(DatabaseConfiguration{
+		Driver: "sqlite3",
+		DBName: filepath.Join(t.TempDir(), "subscribers_database.db"),
+	})
+	if schemaFail := dut.CheckSchema(context.Background()); schemaFail == nil {
+		t.Errorf("ERROR a database without migrations was ready.")
+	}
+	if migrateFail := dut.MigrateUp(); migrateFail != nil {
+		t.Fatal(migrateFail)
+	}
+	if pingFail, schemaFail := dut.Ping(context.Background()), dut.CheckSchema(context.Background()); pingFail != nil ||
+		schemaFail != nil {
+		t.Errorf("ERROR a migrated database was not ready. %v %v", pingFail, schemaFail)
+	}
+	if migrateFail := dut.MigrateDown(1); migrateFail != nil {
+		t.Fatal(migrateFail)
+	}
+	if schemaFail := dut.CheckSchema(context.Background()); nil == schemaFail ||
+		"Schema version 5 is not the latest version 6." != schemaFail.Error() {
+		t.Errorf("ERROR unexpected readiness of a database behind the latest migration. %v", schemaFail)
+	}
+	dut.Close()
+	if pingFail := dut.Ping(context.Background()); pingFail == nil {
+		t.Errorf("ERROR a closed database was reachable.")
+	}
+}
+
+// getHealth gets a health probe of a listening server. The status is 0 when the server does not answer.
+func getHealth(url string) (int, Health) {
+	var health Health
+	response, getFail := http.Get(url)
+	if getFail != nil {
+		return 0, health
+	}
+	defer response.Body.Close()
+	_ = json.NewDecoder(response.Body).Decode(&health)
+	return response.StatusCode, health
+}
+
+func TestServerStartup(t *testing.T) {
+	server, _ := makeServer(makeSubscriberController(&unreadyStore{MemoryRecords: MakeMemoryRecords()}, MVCConfiguration{}),
+		ServerConfiguration{})
+	if defaultDrainDelay != server.drainDelay {
+		t.Errorf("ERROR the server drains for %v instead of %v by default.", server.drainDelay, defaultDrainDelay)
+	}
+	prepared := make(chan error)
+	server.Prepare(func() error {
+		return <-prepared
+	})
+	listener, listenFail := net.Listen("tcp", "127.0.0.1:0")
+	if listenFail != nil {
+		t.Fatal(listenFail)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+	url := "http://" + listener.Addr().String() + "/readyz"
+	if status, health := getHealth(url); http.StatusServiceUnavailable != status || 0 == len(health.Checks) ||
+		"starting" != health.Checks[0].Detail {
+		t.Errorf("ERROR a server that was not prepared answered %v %s", status, ConvertToJson(health))
+	}
+	prepared <- nil
+	status := 0
+	for deadline := time.Now().Add(5 * time.Second); http.StatusOK != status && time.Now().Before(deadline); {
+		status, _ = getHealth(url)
+	}
+	if http.StatusOK != status {
+		t.Errorf("ERROR a prepared server did not get ready: %v", status)
+	}
+	server.Shutdown(context.Background())
+	if serveFail := <-served; serveFail != nil {
+		t.Errorf("ERROR serving failed. %s", serveFail.Error())
+	}
+
+	failing, _ := makeServer(makeSubscriberController(MakeMemoryRecords(), MVCConfiguration{}), ServerConfiguration{})
+	prepareFail := errors.New("Schema version 5 could not be migrated.")
+	failing.Prepare(func() error {
+		return prepareFail
+	})
+	if listener, listenFail = net.Listen("tcp", "127.0.0.1:0"); listenFail != nil {
+		t.Fatal(listenFail)
+	}
+	if serveFail := failing.Serve(listener); serveFail != prepareFail {
+		t.Errorf("ERROR a server that failed to prepare served with %v", serveFail)
+	}
+}
+
+func TestServerDrain(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	server, _ := makeServer(fixture.dut, ServerConfiguration{DrainDelay: time.Second})
+	listener, listenFail := net.Listen("tcp", "127.0.0.1:0")
+	if listenFail != nil {
+		t.Fatal(listenFail)
+	}
+	go server.Serve(listener)
+	url := "http://" + listener.Addr().String()
+	// Without keep-alive, no spare connection holds up the shutdown.
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	get := func(target string) int {
+		response, getFail := client.Get(url + target)
+		if getFail != nil {
+			return 0
+		}
+		response.Body.Close()
+		return response.StatusCode
+	}
+	if status := get("/readyz"); http.StatusOK != status {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	drained := make(chan error, 1)
+	go func() {
+		drained <- server.drain()
+	}()
+	readyStatus := http.StatusOK
+	for deadline := time.Now().Add(500 * time.Millisecond); http.StatusOK == readyStatus && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		readyStatus = get("/readyz")
+	}
+	if apiStatus := get("/subscribers/1"); http.StatusServiceUnavailable != readyStatus || http.StatusOK != apiStatus {
+		t.Errorf("ERROR a draining server answered %v to /readyz and %v to the API.", readyStatus, apiStatus)
+	}
+	if drainFail := <-drained; drainFail != nil {
+		t.Errorf("ERROR draining the server. %s", drainFail.Error())
+	}
+	if status := get("/healthz"); 0 != status {
+		t.Errorf("ERROR the server answered after it was drained: %v", status)
+	}
+	fixture.tearDown()
+}
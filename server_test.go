@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// blockingStore holds lists until it is released and records that it was closed.
+type blockingStore struct {
+	*MemoryRecords
+	listing chan struct{}
+	release chan struct{}
+	closed  bool
+}
+
+func (store *blockingStore) List(ctx context.Context, query ListQuery) ([]Subscriber, error) {
+	store.listing <- struct{}{}
+	<-store.release
+	return store.MemoryRecords.List(ctx, query)
+}
+
+func (store *blockingStore) Close() error {
+	store.closed = true
+	return nil
+}
+
+func TestMakeServer(t *testing.T) {
+	server, _ := makeServer(makeSubscriberController(MakeMemoryRecords(), MVCConfiguration{}), ServerConfiguration{})
+	if defaultAddress != server.Addr() || defaultShutdownTimeout != server.shutdownTimeout || 0 != server.server.ReadTimeout {
+		t.Errorf("ERROR unexpected defaults of the server: %s %s %s", server.Addr(), server.shutdownTimeout,
+			server.server.ReadTimeout)
+	}
+	configuration := ServerConfiguration{Address: "127.0.0.1:8081", ReadTimeout: time.Second, ReadHeaderTimeout: 2 * time.Second,
+		WriteTimeout: 3 * time.Second, IdleTimeout: 4 * time.Second, ShutdownTimeout: 5 * time.Second}
+	server, _ = makeServer(makeSubscriberController(MakeMemoryRecords(), MVCConfiguration{}), configuration)
+	if "127.0.0.1:8081" != server.Addr() || time.Second != server.server.ReadTimeout ||
+		2*time.Second != server.server.ReadHeaderTimeout || 3*time.Second != server.server.WriteTimeout ||
+		4*time.Second != server.server.IdleTimeout || 5*time.Second != server.shutdownTimeout {
+		t.Errorf("ERROR the server does not follow its configuration %+v: %+v", configuration, server.server)
+	}
+
+	fixture := setupSubscriberControllerTestFixture()
+	server, _ = makeServer(fixture.dut, ServerConfiguration{})
+	for target, expectedStatus := range map[string]int{"/subscribers/1": http.StatusOK, "/subscribers/9": http.StatusNotFound,
+		"/subscribers/1/status": http.StatusOK} {
+		response := httptest.NewRecorder()
+		server.Handler().ServeHTTP(response, httptest.NewRequest("GET", target, nil))
+		if status := response.Code; status != expectedStatus {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v", target, status, expectedStatus)
+		}
+	}
+	fixture.tearDown()
+}
+
+func TestServerShutdownDrainsRequests(t *testing.T) {
+	store := &blockingStore{MemoryRecords: MakeMemoryRecords(), listing: make(chan struct{}), release: make(chan struct{})}
+	server, _ := makeServer(makeSubscriberController(store, MVCConfiguration{PageSize: 10, MaxPageSize: 20}),
+		ServerConfiguration{ReadHeaderTimeout: time.Second})
+	listener, listenFail := net.Listen("tcp", "127.0.0.1:0")
+	if listenFail != nil {
+		t.Fatal(listenFail)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+	responded := make(chan int, 1)
+	go func() {
+		response, getFail := http.Get("http://" + listener.Addr().String() + "/subscribers")
+		if getFail != nil {
+			responded <- 0
+			return
+		}
+		response.Body.Close()
+		responded <- response.StatusCode
+	}()
+	<-store.listing
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- server.Shutdown(context.Background())
+	}()
+	select {
+	case <-shutdown:
+		t.Fatal("ERROR the server shut down before the request in flight was answered.")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(store.release)
+	if status := <-responded; http.StatusOK != status {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	if shutdownFail := <-shutdown; shutdownFail != nil || !store.closed {
+		t.Errorf("ERROR shutting down the server. Closed store: %t. %v", store.closed, shutdownFail)
+	}
+	if serveFail := <-served; serveFail != nil {
+		t.Errorf("ERROR a shut down server reported %v", serveFail)
+	}
+	if _, getFail := http.Get("http://" + listener.Addr().String() + "/subscribers"); getFail == nil {
+		t.Errorf("ERROR the server answered after it was shut down.")
+	}
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultReloadInterval = 10 * time.Second
+
+var tlsVersions = map[string]uint16{"": tls.VersionTLS12, "1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13}
+
+// certificateReloader keeps the certificate and the client CAs of a server up to date with their files. Handshakes
+// check the files for changes at most once per reload interval. Files that fail to load leave the previous ones in use.
+type certificateReloader struct {
+	configuration TLSConfiguration
+	mutex         sync.Mutex
+	checkedAt     time.Time
+	modifiedAt    time.Time
+	certificate   *tls.Certificate
+	clientCAs     *x509.CertPool
+}
+
+// makeTLSConfig configures TLS for a server. The server only answers clients with certificates signed by the client
+// CAs, when they are configured.
+func makeTLSConfig(configuration TLSConfiguration) (*tls.Config, error) {
+	minVersion, supported := tlsVersions[configuration.MinVersion]
+	if !supported {
+		return nil, errors.New("Unsupported minimum TLS version " + configuration.MinVersion + ". Please use 1.2 or 1.3.")
+	}
+	if 0 == configuration.ReloadInterval {
+		configuration.ReloadInterval = defaultReloadInterval
+	}
+	reloader := &certificateReloader{configuration: configuration}
+	modifiedAt, statFail := reloader.lastModified()
+	if statFail != nil {
+		return nil, statFail
+	}
+	if loadFail := reloader.load(modifiedAt); loadFail != nil {
+		return nil, loadFail
+	}
+	config := &tls.Config{
+		MinVersion: minVersion,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			certificate, _ := reloader.current()
+			return certificate, nil
+		},
+	}
+	if "" != configuration.ClientCAFile {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			_, clientCAs := reloader.current()
+			clientConfig := config.Clone()
+			clientConfig.ClientCAs = clientCAs
+			clientConfig.GetConfigForClient = nil
+			return clientConfig, nil
+		}
+	}
+	return config, nil
+}
+
+// checkTLSConfiguration refuses TLS settings without a certificate and its key, so that a server never serves plain
+// HTTP when it was meant to check client certificates.
+func checkTLSConfiguration(configuration TLSConfiguration) error {
+	if "" == configuration.CertFile {
+		if "" != configuration.KeyFile || "" != configuration.MinVersion || "" != configuration.ClientCAFile ||
+			0 != len(configuration.AllowedSubjects) || 0 != configuration.ReloadInterval {
+			return errors.New("TLS needs a certificate file. Please set the certfile of the server.")
+		}
+		return nil
+	}
+	if "" == configuration.KeyFile {
+		return errors.New("TLS needs the key file of the certificate. Please set the keyfile of the server.")
+	}
+	return nil
+}
+
+func (reloader *certificateReloader) current() (*tls.Certificate, *x509.CertPool) {
+	reloader.mutex.Lock()
+	defer reloader.mutex.Unlock()
+	if now := time.Now(); now.Sub(reloader.checkedAt) >= reloader.configuration.ReloadInterval {
+		reloader.checkedAt = now
+		modifiedAt, statFail := reloader.lastModified()
+		if statFail == nil && !modifiedAt.Equal(reloader.modifiedAt) {
+			statFail = reloader.load(modifiedAt)
+		}
+		if statFail != nil {
+			log.Print("Keeping the loaded TLS certificates. ", statFail)
+		}
+	}
+	return reloader.certificate, reloader.clientCAs
+}
+
+func (reloader *certificateReloader) lastModified() (time.Time, error) {
+	var modifiedAt time.Time
+	for _, fileName := range []string{reloader.configuration.CertFile, reloader.configuration.KeyFile,
+		reloader.configuration.ClientCAFile} {
+		if "" == fileName {
+			continue
+		}
+		info, statFail := os.Stat(fileName)
+		if statFail != nil {
+			return modifiedAt, statFail
+		}
+		if info.ModTime().After(modifiedAt) {
+			modifiedAt = info.ModTime()
+		}
+	}
+	return modifiedAt, nil
+}
+
+func (reloader *certificateReloader) load(modifiedAt time.Time) error {
+	certificate, certificateFail := tls.LoadX509KeyPair(reloader.configuration.CertFile, reloader.configuration.KeyFile)
+	if certificateFail != nil {
+		return certificateFail
+	}
+	var clientCAs *x509.CertPool
+	if "" != reloader.configuration.ClientCAFile {
+		pem, readFail := ioutil.ReadFile(reloader.configuration.ClientCAFile)
+		if readFail != nil {
+			return readFail
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return errors.New("No client CA certificates in " + reloader.configuration.ClientCAFile + ".")
+		}
+	}
+	reloader.certificate, reloader.clientCAs, reloader.modifiedAt = &certificate, clientCAs, modifiedAt
+	return nil
+}
+
+// authorizing only lets through clients whose verified certificate has one of the subjects, either its common name
+// or its whole distinguished name, e.g. "CN=billing,O=Example".
+func (controller SubscriberController) authorizing(handler http.Handler, subjects []string) http.Handler {
+	allowed := make(map[string]bool, len(subjects))
+	for _, subject := range subjects {
+		allowed[subject] = true
+	}
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		var subject pkix.Name
+		if request.TLS != nil && 0 != len(request.TLS.VerifiedChains) {
+			subject = request.TLS.VerifiedChains[0][0].Subject
+		}
+		if !allowed[subject.CommonName] && !allowed[subject.String()] {
+			controller.sendErrorMessage(response, request, http.StatusForbidden,
+				"The client certificate is not allowed to use the API.")
+			return
+		}
+		handler.ServeHTTP(response, request)
+	})
+}
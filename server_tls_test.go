@@ -0,0 +1,202 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testAuthority struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pem         []byte
+}
+
+var serialNumber int64
+
+// issue makes a certificate for commonName signed by the authority, or a self-signed authority when authority is nil.
+func issue(t *testing.T, authority *testAuthority, commonName string, usage x509.ExtKeyUsage) *testAuthority {
+	key, keyFail := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if keyFail != nil {
+		t.Fatal(keyFail)
+	}
+	serialNumber++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serialNumber),
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"Marc Concepcion"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	parent, signer := template, key
+	if nil == authority {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		template.ExtKeyUsage = nil
+	} else {
+		parent, signer = authority.certificate, authority.key
+	}
+	der, certificateFail := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if certificateFail != nil {
+		t.Fatal(certificateFail)
+	}
+	certificate, _ := x509.ParseCertificate(der)
+	return &testAuthority{certificate, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (authority *testAuthority) keyPEM(t *testing.T) []byte {
+	der, keyFail := x509.MarshalECPrivateKey(authority.key)
+	if keyFail != nil {
+		t.Fatal(keyFail)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func (authority *testAuthority) tlsCertificate(t *testing.T) tls.Certificate {
+	certificate, pairFail := tls.X509KeyPair(authority.pem, authority.keyPEM(t))
+	if pairFail != nil {
+		t.Fatal(pairFail)
+	}
+	return certificate
+}
+
+func writeFile(t *testing.T, fileName string, content []byte, modifiedAt time.Time) {
+	if writeFail := ioutil.WriteFile(fileName, content, 0600); writeFail != nil {
+		t.Fatal(writeFail)
+	}
+	if touchFail := os.Chtimes(fileName, modifiedAt, modifiedAt); touchFail != nil {
+		t.Fatal(touchFail)
+	}
+}
+
+// serveTLS serves a fixture over TLS on a free port and returns its URL.
+func serveTLS(t *testing.T, configuration TLSConfiguration) (string, *Server) {
+	fixture := setupSubscriberControllerTestFixture()
+	server, serverFail := makeServer(fixture.dut, ServerConfiguration{TLS: configuration})
+	if serverFail != nil {
+		t.Fatal(serverFail)
+	}
+	listener, listenFail := net.Listen("tcp", "127.0.0.1:0")
+	if listenFail != nil {
+		t.Fatal(listenFail)
+	}
+	go server.Serve(listener)
+	t.Cleanup(func() {
+		server.Shutdown(context.Background())
+	})
+	return "https://" + listener.Addr().String(), server
+}
+
+func tlsGet(url string, config *tls.Config) (*http.Response, error) {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config, DisableKeepAlives: true}}
+	response, getFail := client.Get(url)
+	if getFail == nil {
+		response.Body.Close()
+	}
+	return response, getFail
+}
+
+func TestServerTLS(t *testing.T) {
+	directory := t.TempDir()
+	authority := issue(t, nil, "Subscribers CA", 0)
+	serverCertificate := issue(t, authority, "127.0.0.1", x509.ExtKeyUsageServerAuth)
+	configuration := TLSConfiguration{CertFile: filepath.Join(directory, "server.pem"),
+		KeyFile: filepath.Join(directory, "server.key"), ReloadInterval: time.Nanosecond}
+	modifiedAt := time.Now().Add(-time.Minute)
+	writeFile(t, configuration.CertFile, serverCertificate.pem, modifiedAt)
+	writeFile(t, configuration.KeyFile, serverCertificate.keyPEM(t), modifiedAt)
+	url, _ := serveTLS(t, configuration)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(authority.certificate)
+	response, getFail := tlsGet(url+"/subscribers/1", &tls.Config{RootCAs: roots})
+	if getFail != nil || http.StatusOK != response.StatusCode ||
+		serverCertificate.certificate.SerialNumber.Cmp(response.TLS.PeerCertificates[0].SerialNumber) != 0 {
+		t.Fatalf("ERROR getting a subscriber over TLS. %v", getFail)
+	}
+	if response, getFail = tlsGet(url+"/subscribers/1", &tls.Config{RootCAs: roots, MaxVersion: tls.VersionTLS11}); getFail == nil {
+		t.Errorf("ERROR the server accepted TLS 1.1.")
+	}
+
+	renewedCertificate := issue(t, authority, "127.0.0.1", x509.ExtKeyUsageServerAuth)
+	writeFile(t, configuration.CertFile, []byte("not a certificate"), time.Now())
+	if response, getFail = tlsGet(url+"/subscribers/1", &tls.Config{RootCAs: roots}); getFail != nil ||
+		serverCertificate.certificate.SerialNumber.Cmp(response.TLS.PeerCertificates[0].SerialNumber) != 0 {
+		t.Errorf("ERROR the server did not keep its certificate when the new one was broken. %v", getFail)
+	}
+	modifiedAt = time.Now().Add(time.Minute)
+	writeFile(t, configuration.KeyFile, renewedCertificate.keyPEM(t), modifiedAt)
+	writeFile(t, configuration.CertFile, renewedCertificate.pem, modifiedAt)
+	if response, getFail = tlsGet(url+"/subscribers/1", &tls.Config{RootCAs: roots}); getFail != nil ||
+		renewedCertificate.certificate.SerialNumber.Cmp(response.TLS.PeerCertificates[0].SerialNumber) != 0 {
+		t.Errorf("ERROR the server did not reload its certificate. %v", getFail)
+	}
+
+	for _, invalid := range []TLSConfiguration{
+		{CertFile: configuration.CertFile, KeyFile: configuration.KeyFile, MinVersion: "1.1"},
+		{CertFile: configuration.CertFile, KeyFile: filepath.Join(directory, "missing.key")},
+		{CertFile: configuration.CertFile, KeyFile: configuration.KeyFile, AllowedSubjects: []string{"billing"}},
+		{AllowedSubjects: []string{"billing"}},
+	} {
+		if _, serverFail := makeServer(setupSubscriberControllerTestFixture().dut, ServerConfiguration{TLS: invalid}); serverFail == nil {
+			t.Errorf("ERROR the server accepted the TLS configuration %+v", invalid)
+		}
+	}
+}
+
+func TestServerMutualTLS(t *testing.T) {
+	directory := t.TempDir()
+	authority := issue(t, nil, "Subscribers CA", 0)
+	serverCertificate := issue(t, authority, "127.0.0.1", x509.ExtKeyUsageServerAuth)
+	configuration := TLSConfiguration{CertFile: filepath.Join(directory, "server.pem"),
+		KeyFile: filepath.Join(directory, "server.key"), ClientCAFile: filepath.Join(directory, "clients.pem"),
+		MinVersion: "1.3", AllowedSubjects: []string{"billing", "CN=reporting,O=Marc Concepcion"}}
+	writeFile(t, configuration.CertFile, serverCertificate.pem, time.Now())
+	writeFile(t, configuration.KeyFile, serverCertificate.keyPEM(t), time.Now())
+	writeFile(t, configuration.ClientCAFile, authority.pem, time.Now())
+	url, _ := serveTLS(t, configuration)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(authority.certificate)
+	if _, getFail := tlsGet(url+"/subscribers", &tls.Config{RootCAs: roots}); getFail == nil {
+		t.Errorf("ERROR the server answered a client without a certificate.")
+	}
+	stranger := issue(t, issue(t, nil, "Another CA", 0), "billing", x509.ExtKeyUsageClientAuth)
+	if _, getFail := tlsGet(url+"/subscribers", &tls.Config{RootCAs: roots,
+		Certificates: []tls.Certificate{stranger.tlsCertificate(t)}}); getFail == nil {
+		t.Errorf("ERROR the server answered a client with a certificate of another CA.")
+	}
+	for commonName, expectedStatus := range map[string]int{"billing": http.StatusOK, "reporting": http.StatusOK,
+		"marketing": http.StatusForbidden} {
+		client := issue(t, authority, commonName, x509.ExtKeyUsageClientAuth)
+		response, getFail := tlsGet(url+"/subscribers", &tls.Config{RootCAs: roots,
+			Certificates: []tls.Certificate{client.tlsCertificate(t)}})
+		if getFail != nil {
+			t.Fatalf("ERROR getting subscribers as %s. %s", commonName, getFail.Error())
+		}
+		if status := response.StatusCode; status != expectedStatus {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v", commonName, status, expectedStatus)
+		}
+	}
+}
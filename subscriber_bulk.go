@@ -0,0 +1,180 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+const bulkBatchSize = 100
+
+const (
+	BulkCreated    = "created"
+	BulkFailed     = "failed"
+	BulkRolledBack = "rolled_back"
+)
+
+type BulkResult struct {
+	Position     int    `json:"position"`
+	EmailAddress string `json:"email_address,omitempty"`
+	Status       string `json:"status"`
+	Error        string `json:"error,omitempty"`
+}
+
+type BulkReport struct {
+	Atomic  bool         `json:"atomic"`
+	Created int          `json:"created"`
+	Failed  int          `json:"failed"`
+	Results []BulkResult `json:"results"`
+}
+
+// CreateSubscribers inserts the subscribers in batches of multi-row statements. Without atomic, a rejected batch is
+// retried one subscriber at a time so that only the offending subscribers fail. With atomic, any failure rolls back
+// every subscriber. The error is only set when the store itself became unusable, e.g. the context expired.
+func CreateSubscribers(ctx context.Context, store SubscriberStore, subscribers []Subscriber, atomic bool) (BulkReport, error) {
+	report := BulkReport{Atomic: atomic, Results: make([]BulkResult, len(subscribers))}
+	var valid []int
+	firstPositions := make(map[string]int)
+	for position, subscriber := range subscribers {
+		report.Results[position] = BulkResult{Position: position, EmailAddress: subscriber.EmailAddress}
+		if "" == subscriber.EmailAddress {
+			report.Results[position].Status = BulkFailed
+			report.Results[position].Error = "Subscriber email_address is required."
+			continue
+		}
+		if first, duplicate := firstPositions[subscriber.EmailAddress]; duplicate {
+			report.Results[position].Status = BulkFailed
+			report.Results[position].Error = "Duplicate email_address of the subscriber at position " + strconv.Itoa(first) + "."
+			continue
+		}
+		firstPositions[subscriber.EmailAddress] = position
+		valid = append(valid, position)
+	}
+	var storeFail error
+	if atomic {
+		storeFail = createSubscribersAtomically(ctx, store, subscribers, valid, &report)
+	} else {
+		storeFail = createSubscribersInBatches(ctx, store, subscribers, valid, &report)
+	}
+	for _, result := range report.Results {
+		switch result.Status {
+		case BulkCreated:
+			report.Created++
+		case BulkFailed:
+			report.Failed++
+		}
+	}
+	return report, storeFail
+}
+
+func createSubscribersAtomically(ctx context.Context, store SubscriberStore, subscribers []Subscriber, valid []int,
+	report *BulkReport) error {
+	rollBack := func() {
+		for position := range report.Results {
+			if "" == report.Results[position].Status {
+				report.Results[position].Status = BulkRolledBack
+			}
+		}
+	}
+	if len(valid) != len(subscribers) {
+		rollBack()
+		return nil
+	}
+	var failedBatch []int
+	transactionFail := store.Transaction(ctx, func(transaction SubscriberStore) error {
+		for _, batch := range batchesOf(valid) {
+			if _, createFail := transaction.CreateBatch(ctx, subscribersAt(subscribers, batch)); createFail != nil {
+				failedBatch = batch
+				return createFail
+			}
+		}
+		return nil
+	})
+	if transactionFail != nil {
+		diagnoseBatch(ctx, store, subscribers, failedBatch, transactionFail, report)
+		rollBack()
+		return ctx.Err()
+	}
+	for _, position := range valid {
+		report.Results[position].Status = BulkCreated
+	}
+	return nil
+}
+
+var errDiagnosis = errors.New("diagnosis is always rolled back")
+
+// diagnoseBatch finds the subscribers that made a batch fail by creating each of them in its own transaction,
+// which is always rolled back. When none of them fails alone, the whole batch is blamed.
+func diagnoseBatch(ctx context.Context, store SubscriberStore, subscribers []Subscriber, batch []int, batchFail error,
+	report *BulkReport) {
+	diagnosed := false
+	for _, position := range batch {
+		var createFail error
+		_ = store.Transaction(ctx, func(transaction SubscriberStore) error {
+			_, createFail = transaction.Create(ctx, subscribers[position])
+			return errDiagnosis
+		})
+		if createFail != nil {
+			report.Results[position].Status = BulkFailed
+			report.Results[position].Error = faultProblem(createFail).Detail
+			diagnosed = true
+		}
+	}
+	if diagnosed {
+		return
+	}
+	for _, position := range batch {
+		report.Results[position].Status = BulkFailed
+		report.Results[position].Error = faultProblem(batchFail).Detail
+	}
+}
+
+func createSubscribersInBatches(ctx context.Context, store SubscriberStore, subscribers []Subscriber, valid []int,
+	report *BulkReport) error {
+	for _, batch := range batchesOf(valid) {
+		if _, createFail := store.CreateBatch(ctx, subscribersAt(subscribers, batch)); createFail == nil {
+			for _, position := range batch {
+				report.Results[position].Status = BulkCreated
+			}
+			continue
+		}
+		for _, position := range batch {
+			if _, createFail := store.Create(ctx, subscribers[position]); createFail != nil {
+				report.Results[position].Status = BulkFailed
+				report.Results[position].Error = faultProblem(createFail).Detail
+			} else {
+				report.Results[position].Status = BulkCreated
+			}
+		}
+		if contextError := ctx.Err(); contextError != nil {
+			return contextError
+		}
+	}
+	return nil
+}
+
+func batchesOf(positions []int) [][]int {
+	var batches [][]int
+	for start := 0; start < len(positions); start += bulkBatchSize {
+		end := start + bulkBatchSize
+		if end > len(positions) {
+			end = len(positions)
+		}
+		batches = append(batches, positions[start:end])
+	}
+	return batches
+}
+
+func subscribersAt(subscribers []Subscriber, positions []int) []Subscriber {
+	batch := make([]Subscriber, 0, len(positions))
+	for _, position := range positions {
+		batch = append(batch, subscribers[position])
+	}
+	return batch
+}
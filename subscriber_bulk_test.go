@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func makeBulkSubscribers(count int) []Subscriber {
+	subscribers := make([]Subscriber, 0, count)
+	for position := 0; position < count; position++ {
+		subscribers = append(subscribers, Subscriber{EmailAddress: "bulk" + strconv.Itoa(position) + "@email.com",
+			FirstName: "Bulk", LastName: strconv.Itoa(position)})
+	}
+	return subscribers
+}
+
+func TestCreateSubscribersInBatches(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		subscribers := makeBulkSubscribers(bulkBatchSize + 50)
+		subscribers[10].EmailAddress = fixture.expectedRecords[0].EmailAddress
+		subscribers[20].EmailAddress = subscribers[19].EmailAddress
+		subscribers[bulkBatchSize+1].EmailAddress = ""
+		report, bulkFail := CreateSubscribers(context.Background(), fixture.dut, subscribers, false)
+		if bulkFail != nil {
+			t.Fatalf("ERROR creating subscribers in bulk. %s", bulkFail.Error())
+		}
+		if len(subscribers)-3 != report.Created || 3 != report.Failed {
+			t.Errorf("ERROR expected %d created and 3 failed, got %d created and %d failed.",
+				len(subscribers)-3, report.Created, report.Failed)
+		}
+		for _, position := range []int{10, 20, bulkBatchSize + 1} {
+			if BulkFailed != report.Results[position].Status || "" == report.Results[position].Error {
+				t.Errorf("ERROR expected the subscriber at position %d to fail, got %v", position, report.Results[position])
+			}
+		}
+		fetchedRecords, listFail := fixture.dut.List(context.Background(), ListQuery{})
+		if listFail != nil {
+			t.Fatalf("ERROR fetching database records. %s", listFail.Error())
+		}
+		if len(fixture.expectedRecords)+report.Created != len(fetchedRecords) {
+			t.Errorf("ERROR expected %d records, got %d.", len(fixture.expectedRecords)+report.Created, len(fetchedRecords))
+		}
+	})
+}
+
+func TestCreateSubscribersAtomically(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		subscribers := makeBulkSubscribers(bulkBatchSize + 50)
+		subscribers[bulkBatchSize+10].EmailAddress = fixture.expectedRecords[0].EmailAddress
+		report, bulkFail := CreateSubscribers(context.Background(), fixture.dut, subscribers, true)
+		if bulkFail != nil {
+			t.Fatalf("ERROR creating subscribers in bulk. %s", bulkFail.Error())
+		}
+		if 0 != report.Created || 0 == report.Failed {
+			t.Errorf("ERROR expected nothing created, got %d created and %d failed.", report.Created, report.Failed)
+		}
+		if 1 != report.Failed || BulkFailed != report.Results[bulkBatchSize+10].Status ||
+			BulkRolledBack != report.Results[0].Status || BulkRolledBack != report.Results[bulkBatchSize+11].Status {
+			t.Errorf("ERROR unexpected results %v, %v and %v", report.Results[bulkBatchSize+10], report.Results[0],
+				report.Results[bulkBatchSize+11])
+		}
+		fetchedRecords, listFail := fixture.dut.List(context.Background(), ListQuery{})
+		if listFail != nil {
+			t.Fatalf("ERROR fetching database records. %s", listFail.Error())
+		}
+		if len(fixture.expectedRecords) != len(fetchedRecords) {
+			t.Errorf("ERROR expected %d records after a rollback, got %d.", len(fixture.expectedRecords), len(fetchedRecords))
+		}
+
+		subscribers[bulkBatchSize+10].EmailAddress = "unique@email.com"
+		report, bulkFail = CreateSubscribers(context.Background(), fixture.dut, subscribers, true)
+		if bulkFail != nil || len(subscribers) != report.Created {
+			t.Errorf("ERROR expected %d created, got %d. %v", len(subscribers), report.Created, bulkFail)
+		}
+	})
+}
+
+func TestBulkCreateController(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	for _, testCase := range []struct {
+		contentType    string
+		body           string
+		rawQuery       string
+		expectedStatus int
+		expectedReport BulkReport
+	}{
+		{"application/json", `[{"email_address": "rey@starwars.com", "first_name": "Rey"}, {"email_address": "finn@starwars.com"}]`,
+			"", http.StatusOK, BulkReport{Created: 2, Results: []BulkResult{
+				{Position: 0, EmailAddress: "rey@starwars.com", Status: BulkCreated},
+				{Position: 1, EmailAddress: "finn@starwars.com", Status: BulkCreated}}}},
+		{"application/x-ndjson", "{\"email_address\": \"poe@starwars.com\"}\n{\"email_address\": \"rey@starwars.com\"}\n",
+			"", http.StatusMultiStatus, BulkReport{Created: 1, Failed: 1, Results: []BulkResult{
+				{Position: 0, EmailAddress: "poe@starwars.com", Status: BulkCreated},
+				{Position: 1, EmailAddress: "rey@starwars.com", Status: BulkFailed,
+					Error: ErrDuplicateEmail.Error()}}}},
+		{"application/json", `[{"email_address": "leia@starwars.com"}, {"email_address": "finn@starwars.com"}]`,
+			"atomic=true", http.StatusUnprocessableEntity, BulkReport{Atomic: true, Failed: 1, Results: []BulkResult{
+				{Position: 0, EmailAddress: "leia@starwars.com", Status: BulkRolledBack},
+				{Position: 1, EmailAddress: "finn@starwars.com", Status: BulkFailed}}}},
+	} {
+		request, fault := http.NewRequest("POST", "/subscribers/bulk?"+testCase.rawQuery, strings.NewReader(testCase.body))
+		if fault != nil {
+			t.Fatal(fault)
+		}
+		request.Header.Set("Content-Type", testCase.contentType)
+		response := httptest.NewRecorder()
+		http.HandlerFunc(fixture.dut.bulkCreate).ServeHTTP(response, request)
+		if status := response.Code; status != testCase.expectedStatus {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, testCase.expectedStatus)
+		}
+		var report BulkReport
+		if decodeFail := json.Unmarshal(response.Body.Bytes(), &report); decodeFail != nil {
+			t.Fatalf("handler returned an invalid report %s. %s", response.Body.String(), decodeFail.Error())
+		}
+		if testCase.expectedReport.Created != report.Created || testCase.expectedReport.Atomic != report.Atomic ||
+			len(testCase.expectedReport.Results) != len(report.Results) {
+			t.Fatalf("handler returned unexpected report: got %v want %v", report, testCase.expectedReport)
+		}
+		for position, expectedResult := range testCase.expectedReport.Results {
+			if "" == expectedResult.Error && BulkFailed == report.Results[position].Status {
+				report.Results[position].Error = ""
+			}
+			if expectedResult != report.Results[position] {
+				t.Errorf("handler returned unexpected result: got %v want %v", report.Results[position], expectedResult)
+			}
+		}
+	}
+
+	for contentType, body := range map[string]string{
+		"text/plain":       `[{"email_address": "han@starwars.com"}]`,
+		"application/json": `[{"email_address": "han@starwars.com", "index": 7}]`,
+	} {
+		request, fault := http.NewRequest("POST", "/subscribers/bulk", strings.NewReader(body))
+		if fault != nil {
+			t.Fatal(fault)
+		}
+		request.Header.Set("Content-Type", contentType)
+		response := httptest.NewRecorder()
+		http.HandlerFunc(fixture.dut.bulkCreate).ServeHTTP(response, request)
+		if status := response.Code; status != http.StatusUnsupportedMediaType && status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code for %s: got %v", body, status)
+		}
+	}
+	fixture.tearDown()
+}
@@ -13,12 +13,22 @@ import (
 	"github.com/gorilla/mux"
 	"io"
 	"log"
+	"mime"
 	"net/http"
+	"path"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type SubscriberController struct {
-	model Records
+	model          SubscriberStore
+	pageSize       int
+	maxPageSize    int
+	requireIfMatch bool
+	purgeRetention time.Duration
+	publicIDs      bool
 }
 
 type Message struct {
@@ -31,189 +41,743 @@ type Update struct {
 	Updates Subscriber `json:"updates"`
 }
 
+type subscriberForm struct {
+	EmailAddress string `json:"email_address"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+}
+
+const bulkBodyLimit = 16 << 20
+
 func (controller SubscriberController) list(response http.ResponseWriter, request *http.Request) {
-	subscribers, recordsError := controller.model.list()
+	query, cursorMode, queryError := parsePageQuery(request.URL.Query(), controller.pageSize, controller.maxPageSize,
+		func(key string) (uint64, error) {
+			return controller.subscriberIndex(request, "cursor", key)
+		})
+	if queryError == nil {
+		queryError = parseListFilters(request.URL.Query(), &query)
+	}
+	if queryError != nil {
+		controller.sendError(response, request, queryError)
+		return
+	}
+	pageQuery := query
+	pageQuery.Limit++
+	subscribers, recordsError := controller.model.List(request.Context(), pageQuery)
 	if recordsError != nil {
-		controller.sendErrorMessage(http.StatusInternalServerError, response, recordsError.Error())
+		controller.sendError(response, request, recordsError)
 		return
 	}
-	jsonSubscribers, jsonError := json.Marshal(subscribers)
-	if jsonError != nil {
-		log.Panic(jsonError)
+	hasMore := len(subscribers) > query.Limit
+	if hasMore && 0 != query.Before {
+		subscribers = subscribers[1:]
+	} else if hasMore {
+		subscribers = subscribers[:query.Limit]
+	}
+	total, recordsError := controller.model.Count(request.Context(), query)
+	if recordsError != nil {
+		controller.sendError(response, request, recordsError)
 		return
 	}
-	_, ioError := io.WriteString(response, string(jsonSubscribers))
-	if ioError != nil {
-		log.Panic(ioError)
+	page := makePage(subscribers, total, query, cursorMode, hasMore, controller.publicIDs, *request.URL)
+	if links := page.Links.header(); "" != links {
+		response.Header().Set("Link", links)
 	}
+	response.Header().Set("X-Total-Count", strconv.Itoa(total))
+	controller.send(response, request, http.StatusOK, page, subscriberMediaTypes...)
 }
 
 func (controller SubscriberController) create(response http.ResponseWriter, request *http.Request) {
-	if 0 == len(request.URL.Query()) {
-		controller.sendErrorMessage(http.StatusMethodNotAllowed, response,
-			"HTTP command POST without providing parameters is not allowed. Please provide an acceptable HTTP command.")
+	fields, fieldsError := subscriberFields(response, request, "email_address", "first_name", "last_name",
+		"activation_flag")
+	if fieldsError != nil {
+		controller.sendError(response, request, fieldsError)
+		return
+	}
+	if 0 == len(fields) {
+		controller.sendErrorMessage(response, request, http.StatusBadRequest,
+			"HTTP command POST without providing parameters is not allowed. Please provide the subscriber to create.")
 		return
 	}
-	emailAddress := request.URL.Query().Get("email_address")
-	firstName := request.URL.Query().Get("first_name")
-	lastName := request.URL.Query().Get("last_name")
 	subscriber := Subscriber{}
-	subscriber.LastName = lastName
-	subscriber.FirstName = firstName
-	subscriber.EmailAddress = emailAddress
-	_, recordsError := controller.model.create(subscriber)
+	subscriber.LastName = fields["last_name"]
+	subscriber.FirstName = fields["first_name"]
+	subscriber.EmailAddress = fields["email_address"]
+	if "" == subscriber.EmailAddress {
+		controller.sendError(response, request, ValidationError{"email_address", "Subscriber email_address is required."})
+		return
+	}
+	var index uint64
+	var recordsError error
+	switch fields["activation_flag"] {
+	case "true":
+		index, recordsError = CreateActivatedSubscriber(request.Context(), controller.model, subscriber)
+	case "", "false":
+		var result sql.Result
+		if result, recordsError = controller.model.Create(request.Context(), subscriber); recordsError == nil {
+			var lastInsertId int64
+			lastInsertId, recordsError = result.LastInsertId()
+			index = uint64(lastInsertId)
+		}
+	default:
+		controller.sendError(response, request, ValidationError{"activation_flag",
+			"The activation_flag of a new subscriber can only be 'true' or 'false'."})
+		return
+	}
 	if recordsError != nil {
-		controller.sendErrorMessage(http.StatusInternalServerError, response, recordsError.Error())
+		controller.sendError(response, request, controller.inTrash(request, subscriber.EmailAddress, recordsError))
+		return
+	}
+	persisted, recordsError := controller.model.Retrieve(request.Context(), index)
+	if recordsError != nil {
+		controller.sendError(response, request, recordsError)
 		return
 	}
 
-	jsonSubscriber, jsonError := json.Marshal(Update{"Record created", subscriber})
-	if jsonError != nil {
-		log.Panic(jsonError)
+	location := strconv.FormatUint(index, 10)
+	if controller.publicIDs {
+		location = persisted.PublicID
+	}
+	response.Header().Set("Location", path.Join(request.URL.Path, location))
+	controller.send(response, request, http.StatusCreated, Update{"Record created", *persisted})
+}
+
+func (controller SubscriberController) bulkCreate(response http.ResponseWriter, request *http.Request) {
+	atomic := false
+	switch request.URL.Query().Get("atomic") {
+	case "true":
+		atomic = true
+	case "", "false":
+	default:
+		controller.sendError(response, request, ValidationError{"atomic", "Please set atomic to 'true' or 'false'."})
 		return
 	}
-	_, ioError := io.WriteString(response, string(jsonSubscriber))
-	if ioError != nil {
-		log.Panic(ioError)
+	mediaType, _, mediaTypeError := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if mediaTypeError != nil || ("application/json" != mediaType && "application/x-ndjson" != mediaType) {
+		controller.sendErrorMessage(response, request, http.StatusUnsupportedMediaType,
+			"Please send subscribers as a JSON array (application/json) or as NDJSON (application/x-ndjson).")
+		return
+	}
+	decoder := json.NewDecoder(http.MaxBytesReader(response, request.Body, bulkBodyLimit))
+	decoder.DisallowUnknownFields()
+	var forms []subscriberForm
+	var decodeError error
+	if "application/json" == mediaType {
+		if decodeError = decoder.Decode(&forms); decodeError == nil && decoder.More() {
+			decodeError = errors.New("unexpected data after the JSON array")
+		}
+	} else {
+		for decoder.More() {
+			var form subscriberForm
+			if decodeError = decoder.Decode(&form); decodeError != nil {
+				break
+			}
+			forms = append(forms, form)
+		}
+	}
+	if decodeError != nil {
+		controller.sendErrorMessage(response, request, http.StatusBadRequest, "Invalid subscribers. "+decodeError.Error())
+		return
+	}
+	if 0 == len(forms) {
+		controller.sendErrorMessage(response, request, http.StatusBadRequest, "Please provide at least one subscriber.")
+		return
+	}
+	subscribers := make([]Subscriber, 0, len(forms))
+	for _, form := range forms {
+		subscribers = append(subscribers, Subscriber{EmailAddress: form.EmailAddress, FirstName: form.FirstName,
+			LastName: form.LastName})
+	}
+	report, recordsError := CreateSubscribers(request.Context(), controller.model, subscribers, atomic)
+	if recordsError != nil {
+		controller.sendError(response, request, recordsError)
+		return
+	}
+
+	status := http.StatusOK
+	switch {
+	case 0 == report.Failed:
+	case atomic:
+		status = http.StatusUnprocessableEntity
+	default:
+		status = http.StatusMultiStatus
 	}
+	controller.send(response, request, status, report)
 }
 
 func (controller SubscriberController) retrieve(response http.ResponseWriter, request *http.Request) {
-	index, indexError := strconv.Atoi(mux.Vars(request)["index"])
+	index, indexError := controller.subscriberIndex(request, "index", mux.Vars(request)["index"])
 	if indexError != nil {
-		controller.sendErrorMessage(http.StatusBadRequest, response, indexError.Error())
+		controller.sendError(response, request, indexError)
 		return
 	}
-	subscriber, recordsError := controller.model.retrieve(uint8(index))
+	mediaType, negotiateError := negotiate(request.Header.Get("Accept"), subscriberMediaTypes)
+	if negotiateError != nil {
+		controller.sendError(response, request, negotiateError)
+		return
+	}
+	// The version is read first, so that a concurrent change can only make the ETag older than the subscriber.
+	version, recordsError := controller.model.Version(request.Context(), index)
 	if recordsError != nil {
-		if errors.Is(recordsError, sql.ErrNoRows) {
-			controller.sendErrorMessage(http.StatusNotFound, response, "Subscriber does not exist.")
-		} else {
-			controller.sendErrorMessage(http.StatusInternalServerError, response, recordsError.Error())
-		}
+		controller.sendError(response, request, recordsError)
 		return
 	}
-	jsonSubscriber, jsonError := json.Marshal(subscriber)
-	if jsonError != nil {
-		log.Panic(jsonError)
+	tag := entityTag(version, mediaType)
+	if noneMatch(request.Header.Get("If-None-Match"), tag) {
+		response.Header().Set("ETag", tag)
+		response.Header().Add("Vary", "Accept")
+		response.WriteHeader(http.StatusNotModified)
 		return
 	}
-	_, ioError := io.WriteString(response, string(jsonSubscriber))
-	if ioError != nil {
-		log.Panic(ioError)
+	subscriber, recordsError := controller.model.Retrieve(request.Context(), index)
+	if recordsError != nil {
+		controller.sendError(response, request, recordsError)
+		return
 	}
+	response.Header().Set("ETag", tag)
+	if subscriber.UpdatedAt != nil {
+		response.Header().Set("Last-Modified", lastModified(*subscriber.UpdatedAt))
+	}
+	// If-Modified-Since only counts when there is no If-None-Match.
+	if "" == request.Header.Get("If-None-Match") &&
+		notModifiedSince(request.Header.Get("If-Modified-Since"), subscriber.UpdatedAt) {
+		response.Header().Add("Vary", "Accept")
+		response.WriteHeader(http.StatusNotModified)
+		return
+	}
+	controller.send(response, request, http.StatusOK, subscriber, subscriberMediaTypes...)
 }
 
 func (controller SubscriberController) update(response http.ResponseWriter, request *http.Request) {
-	subscriber := Subscriber{}
-	index, indexError := strconv.Atoi(mux.Vars(request)["index"])
+	index, indexError := controller.subscriberIndex(request, "index", mux.Vars(request)["index"])
 	if indexError != nil {
-		controller.sendErrorMessage(http.StatusBadRequest, response, indexError.Error())
+		controller.sendError(response, request, indexError)
 		return
 	}
-	subscriber.Index = uint8(index)
-	if 0 == len(request.URL.Query()) {
-		controller.sendErrorMessage(http.StatusMethodNotAllowed, response,
-			"HTTP command PUT without providing parameters is not allowed. Please provide an acceptable HTTP command.")
+	fields, fieldsError := subscriberFields(response, request, subscriberUpdateFields...)
+	if fieldsError != nil {
+		controller.sendError(response, request, fieldsError)
 		return
 	}
-	emailAddress := request.URL.Query().Get("email_address")
-	subscriber.EmailAddress = emailAddress
-	firstName := request.URL.Query().Get("first_name")
-	subscriber.FirstName = firstName
-	lastName := request.URL.Query().Get("last_name")
-	subscriber.LastName = lastName
-	_, recordsError := controller.model.update(subscriber)
-	if recordsError != nil {
-		controller.sendErrorMessage(http.StatusInternalServerError, response, recordsError.Error())
+	if 0 == len(fields) {
+		controller.sendErrorMessage(response, request, http.StatusBadRequest,
+			"HTTP command PUT without providing parameters is not allowed. Please provide the fields to update.")
 		return
 	}
-
-	jsonSubscriber, jsonError := json.Marshal(Update{"Record updated", subscriber})
-	if jsonError != nil {
-		log.Panic(jsonError)
+	update, updateError := MakeSubscriberUpdate(index, fields)
+	if updateError != nil {
+		controller.sendError(response, request, updateError)
 		return
 	}
-	_, ioError := io.WriteString(response, string(jsonSubscriber))
-	if ioError != nil {
-		log.Panic(ioError)
+	var result sql.Result
+	version, recordsError := controller.guarded(request, index, func(store SubscriberStore) error {
+		var updateFail error
+		result, updateFail = store.Update(request.Context(), update)
+		return updateFail
+	})
+	if recordsError != nil {
+		controller.sendError(response, request, controller.inTrash(request, fields["email_address"], recordsError))
+		return
+	}
+	if !controller.affected(response, request, result) {
+		return
 	}
+	controller.setEntityTag(response, version)
+	controller.send(response, request, http.StatusOK, Update{"Record updated", update.subscriber()})
 }
 
 func (controller SubscriberController) delete(response http.ResponseWriter, request *http.Request) {
-	index, indexError := strconv.Atoi(mux.Vars(request)["index"])
+	index, indexError := controller.subscriberIndex(request, "index", mux.Vars(request)["index"])
 	if indexError != nil {
-		controller.sendErrorMessage(http.StatusBadRequest, response, indexError.Error())
+		controller.sendError(response, request, indexError)
 		return
 	}
-	_, recordsError := controller.model.delete(uint8(index))
+	var result sql.Result
+	_, recordsError := controller.guarded(request, index, func(store SubscriberStore) error {
+		var deleteFail error
+		result, deleteFail = store.Delete(request.Context(), index)
+		return deleteFail
+	})
 	if recordsError != nil {
-		controller.sendErrorMessage(http.StatusInternalServerError, response, recordsError.Error())
+		controller.sendError(response, request, recordsError)
 		return
 	}
+	if controller.affected(response, request, result) {
+		response.WriteHeader(http.StatusNoContent)
+	}
+}
 
-	jsonSubscriber, jsonError := json.Marshal(Message{"success", "Deleted record of subscriber #" + strconv.Itoa(index)})
-	if jsonError != nil {
-		log.Panic(jsonError)
+func (controller SubscriberController) patch(response http.ResponseWriter, request *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	var makePatch func([]byte) (func(map[string]interface{}) (map[string]interface{}, error), error)
+	switch mediaType {
+	case mergePatchMediaType:
+		makePatch = mergePatch
+	case jsonPatchMediaType:
+		makePatch = jsonPatch
+	default:
+		controller.activate(response, request)
 		return
 	}
-	_, ioError := io.WriteString(response, string(jsonSubscriber))
+	index, indexError := controller.subscriberIndex(request, "index", mux.Vars(request)["index"])
+	if indexError != nil {
+		controller.sendError(response, request, indexError)
+		return
+	}
+	body, ioError := io.ReadAll(http.MaxBytesReader(response, request.Body, bodyLimit))
 	if ioError != nil {
-		log.Panic(ioError)
+		controller.sendError(response, request, bodyError(ioError))
+		return
+	}
+	patch, patchError := makePatch(body)
+	if patchError != nil {
+		controller.sendError(response, request, patchError)
+		return
+	}
+	var subscriber Subscriber
+	version, recordsError := controller.guarded(request, index, func(store SubscriberStore) error {
+		var patchFail error
+		subscriber, patchFail = PatchSubscriber(request.Context(), store, index, patch)
+		return patchFail
+	})
+	if recordsError != nil {
+		controller.sendError(response, request, recordsError)
+		return
 	}
+	controller.setEntityTag(response, version)
+
+	controller.send(response, request, http.StatusOK, Update{"Record patched", subscriber})
 }
 
 func (controller SubscriberController) activate(response http.ResponseWriter, request *http.Request) {
-	index, indexError := strconv.Atoi(mux.Vars(request)["index"])
+	index, indexError := controller.subscriberIndex(request, "index", mux.Vars(request)["index"])
 	if indexError != nil {
-		controller.sendErrorMessage(http.StatusBadRequest, response, indexError.Error())
+		controller.sendError(response, request, indexError)
 		return
 	}
-	activateString := request.URL.Query().Get("activation_flag")
+	fields, fieldsError := subscriberFields(response, request, "activation_flag")
+	if fieldsError != nil {
+		controller.sendError(response, request, fieldsError)
+		return
+	}
+	activateString := fields["activation_flag"]
 	activate := false
-	if activateString == "true" {
+	activated := "deactivated"
+	switch activateString {
+	case "true":
 		activate = true
-	} else {
-		controller.sendErrorMessage(http.StatusBadRequest, response,
-			"Only activating a subscriber is allowed. Please set the activation_flag to 'true'.")
+		activated = "activated"
+	case "false":
+	default:
+		controller.sendError(response, request, ValidationError{"activation_flag",
+			"Please set the activation_flag to 'true' or 'false'."})
 		return
 	}
-	_, recordsError := controller.model.activate(uint8(index), activate)
+	var result sql.Result
+	version, recordsError := controller.guarded(request, index, func(store SubscriberStore) error {
+		var activateFail error
+		result, activateFail = store.Activate(request.Context(), index, activate)
+		return activateFail
+	})
 	if recordsError != nil {
-		controller.sendErrorMessage(http.StatusInternalServerError, response, recordsError.Error())
+		controller.sendError(response, request, recordsError)
+		return
+	}
+	if !controller.affected(response, request, result) {
 		return
 	}
 
-	jsonSubscriber, jsonError := json.Marshal(Message{"success", "Record #" + strconv.Itoa(index) + " activated."})
-	if jsonError != nil {
-		log.Panic(jsonError)
+	controller.setEntityTag(response, version)
+	controller.send(response, request, http.StatusOK, Message{"success", "Record " +
+		reference(mux.Vars(request)["index"], index) + " " + activated + "."})
+}
+
+func (controller SubscriberController) retrieveStatus(response http.ResponseWriter, request *http.Request) {
+	index, indexError := controller.subscriberIndex(request, "index", mux.Vars(request)["index"])
+	if indexError != nil {
+		controller.sendError(response, request, indexError)
 		return
 	}
-	_, ioError := io.WriteString(response, string(jsonSubscriber))
+	lifecycle, recordsError := controller.model.Lifecycle(request.Context(), index)
+	if recordsError != nil {
+		controller.sendError(response, request, recordsError)
+		return
+	}
+	controller.send(response, request, http.StatusOK, lifecycle)
+}
+
+func (controller SubscriberController) transition(response http.ResponseWriter, request *http.Request) {
+	index, indexError := controller.subscriberIndex(request, "index", mux.Vars(request)["index"])
+	if indexError != nil {
+		controller.sendError(response, request, indexError)
+		return
+	}
+	fields, fieldsError := subscriberFields(response, request, "status")
+	if fieldsError != nil {
+		controller.sendError(response, request, fieldsError)
+		return
+	}
+	status := fields["status"]
+	if !validStatus(status) {
+		controller.sendError(response, request, checkTransition("", status))
+		return
+	}
+	var result sql.Result
+	version, recordsError := controller.guarded(request, index, func(store SubscriberStore) error {
+		var transitionFail error
+		result, transitionFail = store.Transition(request.Context(), index, status)
+		return transitionFail
+	})
+	if recordsError != nil {
+		controller.sendError(response, request, recordsError)
+		return
+	}
+	if controller.affected(response, request, result) {
+		controller.setEntityTag(response, version)
+		controller.retrieveStatus(response, request)
+	}
+}
+
+func (controller SubscriberController) swapEmailAddress(response http.ResponseWriter, request *http.Request) {
+	index, indexError := controller.subscriberIndex(request, "index", mux.Vars(request)["index"])
+	if indexError != nil {
+		controller.sendError(response, request, indexError)
+		return
+	}
+	with := request.URL.Query().Get("with")
+	if "" == with {
+		controller.sendError(response, request, ValidationError{"with",
+			"Please set 'with' to the subscriber to swap email addresses with."})
+		return
+	}
+	other, otherError := controller.subscriberIndex(request, "with", with)
+	if otherError != nil {
+		controller.sendError(response, request, otherError)
+		return
+	}
+	if index == other {
+		controller.sendError(response, request, ValidationError{"with",
+			"A subscriber cannot swap email addresses with itself."})
+		return
+	}
+	recordsError := SwapEmailAddresses(request.Context(), controller.model, index, other)
+	if recordsError != nil {
+		controller.sendError(response, request, recordsError)
+		return
+	}
+
+	controller.send(response, request, http.StatusOK, Message{"success",
+		"Swapped email addresses of subscribers " + reference(mux.Vars(request)["index"], index) + " and " +
+			reference(with, other) + "."})
+}
+
+func (controller SubscriberController) restore(response http.ResponseWriter, request *http.Request) {
+	index, indexError := controller.subscriberIndex(request, "index", mux.Vars(request)["index"])
+	if indexError != nil {
+		controller.sendError(response, request, indexError)
+		return
+	}
+	var restored *Subscriber
+	var version uint64
+	recordsError := controller.model.Transaction(request.Context(), func(store SubscriberStore) error {
+		result, restoreFail := store.Restore(request.Context(), index)
+		if restoreFail != nil {
+			return restoreFail
+		}
+		if rowsAffected, _ := result.RowsAffected(); 0 == rowsAffected {
+			return requestError{http.StatusNotFound, "", "Subscriber " +
+				reference(mux.Vars(request)["index"], index) + " is not in the trash."}
+		}
+		if version, restoreFail = store.Version(request.Context(), index); restoreFail != nil {
+			return restoreFail
+		}
+		restored, restoreFail = store.Retrieve(request.Context(), index)
+		return restoreFail
+	})
+	if recordsError != nil {
+		controller.sendError(response, request, recordsError)
+		return
+	}
+	controller.setEntityTag(response, version)
+	controller.send(response, request, http.StatusOK, Update{"Record restored", *restored})
+}
+
+// purge permanently removes the subscribers that have been in the trash for longer than the purge retention.
+func (controller SubscriberController) purge(response http.ResponseWriter, request *http.Request) {
+	deletedBefore := storeTime().Add(-controller.purgeRetention)
+	result, recordsError := controller.model.Purge(request.Context(), deletedBefore)
+	if recordsError != nil {
+		controller.sendError(response, request, recordsError)
+		return
+	}
+	purged, rowsAffectedError := result.RowsAffected()
+	if rowsAffectedError != nil {
+		controller.sendError(response, request, rowsAffectedError)
+		return
+	}
+	controller.send(response, request, http.StatusOK, Message{"success", "Purged " + strconv.FormatInt(purged, 10) +
+		" subscribers deleted before " + deletedBefore.Format(time.RFC3339) + "."})
+}
+
+func (controller SubscriberController) sendErrorMessage(response http.ResponseWriter, request *http.Request,
+	httpStatusCode int, errorMessage string) {
+	controller.sendProblem(response, request, makeProblem(httpStatusCode, errorMessage))
+}
+
+// send answers with value in the media type that the request accepts among offers, which defaults to JSON.
+func (controller SubscriberController) send(response http.ResponseWriter, request *http.Request, status int,
+	value interface{}, offers ...string) {
+	if 0 == len(offers) {
+		offers = []string{jsonMediaType}
+	}
+	mediaType, negotiateError := negotiate(request.Header.Get("Accept"), offers)
+	if negotiateError != nil {
+		controller.sendError(response, request, negotiateError)
+		return
+	}
+	body, encodeError := encode(mediaType, controller.public(value))
+	if encodeError != nil {
+		log.Panic(encodeError)
+	}
+	if len(offers) > 1 {
+		response.Header().Add("Vary", "Accept")
+	}
+	response.Header().Set("Content-Type", contentTypes[mediaType])
+	response.WriteHeader(status)
+	_, ioError := response.Write(body)
 	if ioError != nil {
 		log.Panic(ioError)
 	}
 }
 
-func (controller SubscriberController) sendErrorMessage(httpStatusCode int, response http.ResponseWriter, errorMessage string) {
-	jsonErrorMessage, jsonError := json.Marshal(Message{"error", errorMessage})
+// accepting answers 406 before the handler runs when the request accepts none of the offers.
+func (controller SubscriberController) accepting(handler http.HandlerFunc, offers ...string) http.HandlerFunc {
+	if 0 == len(offers) {
+		offers = []string{jsonMediaType}
+	}
+	return func(response http.ResponseWriter, request *http.Request) {
+		if _, negotiateError := negotiate(request.Header.Get("Accept"), offers); negotiateError != nil {
+			controller.sendError(response, request, negotiateError)
+			return
+		}
+		handler(response, request)
+	}
+}
+
+// sendError answers with the problem of an error. See faultProblem.
+func (controller SubscriberController) sendError(response http.ResponseWriter, request *http.Request, fault error) {
+	controller.sendProblem(response, request, faultProblem(fault))
+}
+
+func (controller SubscriberController) sendProblem(response http.ResponseWriter, request *http.Request, problem Problem) {
+	problem.Instance = request.URL.Path
+	jsonProblem, jsonError := json.Marshal(problem)
 	if jsonError != nil {
 		log.Panic(jsonError)
 	}
-	http.Error(response, string(jsonErrorMessage), httpStatusCode)
+	response.Header().Set("Content-Type", problemMediaType)
+	response.Header().Set("X-Content-Type-Options", "nosniff")
+	response.WriteHeader(problem.Status)
+	_, ioError := io.WriteString(response, string(jsonProblem))
+	if ioError != nil {
+		log.Panic(ioError)
+	}
 }
 
-func MakeSubscriberController(model Records) SubscriberController {
-	return SubscriberController{model}
+// guarded runs a command on a subscriber in a transaction. When the request has an If-Match header, the command only
+// runs on the versions it names. It returns the version of the subscriber after the command, or 0 once it is gone.
+func (controller SubscriberController) guarded(request *http.Request, index uint64,
+	command func(store SubscriberStore) error) (uint64, error) {
+	var version uint64
+	versioned := func(store SubscriberStore) error {
+		if commandFail := command(store); commandFail != nil {
+			return commandFail
+		}
+		var versionFail error
+		if version, versionFail = store.Version(request.Context(), index); errors.Is(versionFail, ErrNotFound) {
+			return nil
+		}
+		return versionFail
+	}
+	ifMatch := request.Header.Get("If-Match")
+	if "" != ifMatch {
+		return version, IfVersion(request.Context(), controller.model, index, versionMatcher(ifMatch), versioned)
+	}
+	if controller.requireIfMatch {
+		return version, requestError{http.StatusPreconditionRequired, "",
+			"Please send the ETag of the subscriber in an If-Match header."}
+	}
+	return version, controller.model.Transaction(request.Context(), versioned)
 }
 
-func (controller SubscriberController) ViewHandleRequests() {
+// subscriberIndex finds the subscriber that a request names by index or by public ID. With public IDs only, indexes
+// name no subscriber.
+func (controller SubscriberController) subscriberIndex(request *http.Request, field string, value string) (uint64, error) {
+	if publicID, isPublicID := parsePublicID(value); isPublicID {
+		return controller.model.Resolve(request.Context(), publicID)
+	}
+	if controller.publicIDs {
+		return 0, ErrNotFound
+	}
+	return parseIndex(field, value)
+}
+
+// inTrash tells apart an email address that is taken by a deleted subscriber, which a client may rather restore.
+func (controller SubscriberController) inTrash(request *http.Request, emailAddress string, fault error) error {
+	if "" == emailAddress || !errors.Is(fault, ErrDuplicateEmail) {
+		return fault
+	}
+	deleted, listFail := controller.model.List(request.Context(),
+		ListQuery{Limit: 1, Deleted: true, EmailAddress: emailAddress})
+	if listFail != nil || 0 == len(deleted) {
+		return fault
+	}
+	id := strconv.FormatUint(deleted[0].Index, 10)
+	if controller.publicIDs {
+		id = deleted[0].PublicID
+	}
+	return deletedEmailError{"/subscribers/" + id + "/restore", fault}
+}
+
+// reference names a subscriber in messages the way the request did.
+func reference(value string, index uint64) string {
+	if publicID, isPublicID := parsePublicID(value); isPublicID {
+		return publicID
+	}
+	return "#" + strconv.FormatUint(index, 10)
+}
+
+// public leaves the indexes out of the subscribers in a response when subscribers are only known by public ID.
+func (controller SubscriberController) public(value interface{}) interface{} {
+	if !controller.publicIDs {
+		return value
+	}
+	switch typedValue := value.(type) {
+	case *Subscriber:
+		subscriber := *typedValue
+		subscriber.Index = 0
+		return subscriber
+	case Update:
+		typedValue.Updates.Index = 0
+		return typedValue
+	case Page:
+		subscribers := make([]Subscriber, 0, len(typedValue.Subscribers))
+		for _, subscriber := range typedValue.Subscribers {
+			subscriber.Index = 0
+			subscribers = append(subscribers, subscriber)
+		}
+		typedValue.Subscribers = subscribers
+		return typedValue
+	case *SubscriberLifecycle:
+		lifecycle := *typedValue
+		lifecycle.Index = 0
+		return lifecycle
+	}
+	return value
+}
+
+// The ETag of a changed subscriber is the one of its JSON representation.
+func (controller SubscriberController) setEntityTag(response http.ResponseWriter, version uint64) {
+	if 0 != version {
+		response.Header().Set("ETag", entityTag(version, jsonMediaType))
+	}
+}
+
+// affected answers 404 when a command found no subscriber to change.
+func (controller SubscriberController) affected(response http.ResponseWriter, request *http.Request, result sql.Result) bool {
+	rowsAffected, rowsAffectedError := result.RowsAffected()
+	if rowsAffectedError != nil {
+		controller.sendError(response, request, rowsAffectedError)
+		return false
+	}
+	if 0 == rowsAffected {
+		controller.sendError(response, request, ErrNotFound)
+		return false
+	}
+	return true
+}
+
+func MakeSubscriberController(model SubscriberStore) SubscriberController {
+	return makeSubscriberController(model, settings().MVC)
+}
+
+func makeSubscriberController(model SubscriberStore, configuration MVCConfiguration) SubscriberController {
+	purgeRetention := configuration.PurgeRetention
+	if 0 == purgeRetention {
+		purgeRetention = defaultPurgeRetention
+	}
+	if 0 == configuration.MaxPageSize {
+		configuration.MaxPageSize = defaultMaxPageSize
+	}
+	if 0 == configuration.PageSize {
+		configuration.PageSize = defaultPageSize
+	}
+	if configuration.PageSize > configuration.MaxPageSize {
+		configuration.PageSize = configuration.MaxPageSize
+	}
+	return SubscriberController{model, configuration.PageSize, configuration.MaxPageSize, configuration.RequireIfMatch,
+		purgeRetention, configuration.PublicIDs}
+}
+
+// Handler routes the requests of the API to the controller.
+func (controller SubscriberController) Handler() http.Handler {
 	router := mux.NewRouter().StrictSlash(true)
-	router.HandleFunc("/subscribers", controller.list).Methods("GET")
-	router.HandleFunc("/subscribers", controller.create).Methods("POST")
-	router.HandleFunc("/subscribers/{index}", controller.update).Methods("PUT")
-	router.HandleFunc("/subscribers/{index}", controller.activate).Methods("PATCH")
+	router.HandleFunc("/subscribers", controller.accepting(controller.list, subscriberMediaTypes...)).Methods("GET")
+	router.HandleFunc("/subscribers", controller.accepting(controller.create)).Methods("POST")
+	router.HandleFunc("/subscribers/bulk", controller.accepting(controller.bulkCreate)).Methods("POST")
+	router.HandleFunc("/subscribers/purge", controller.accepting(controller.purge)).Methods("POST")
+	router.HandleFunc("/subscribers/{index}", controller.accepting(controller.update)).Methods("PUT")
+	router.HandleFunc("/subscribers/{index}", controller.accepting(controller.patch)).Methods("PATCH")
 	router.HandleFunc("/subscribers/{index}", controller.delete).Methods("DELETE")
-	router.HandleFunc("/subscribers/{index}", controller.retrieve).Methods("GET")
-	log.Fatal(http.ListenAndServe(":8080", router))
+	router.HandleFunc("/subscribers/{index}", controller.accepting(controller.retrieve, subscriberMediaTypes...)).Methods("GET")
+	router.HandleFunc("/subscribers/{index}/status", controller.accepting(controller.retrieveStatus)).Methods("GET")
+	router.HandleFunc("/subscribers/{index}/status", controller.accepting(controller.transition)).Methods("PUT")
+	router.HandleFunc("/subscribers/{index}/restore", controller.accepting(controller.restore)).Methods("POST")
+	router.HandleFunc("/subscribers/{index}/swap_email_address", controller.accepting(controller.swapEmailAddress)).Methods("POST")
+	router.NotFoundHandler = http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		controller.sendErrorMessage(response, request, http.StatusNotFound,
+			"The API only serves the subscribers resource at /subscribers.")
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.Header().Set("Allow", strings.Join(allowedMethods(router, request), ", "))
+		controller.sendErrorMessage(response, request, http.StatusMethodNotAllowed,
+			"HTTP command "+request.Method+" is not allowed on "+request.URL.Path+".")
+	})
+	return router
+}
+
+// allowedMethods lists the methods of the routes that match the path of the request.
+func allowedMethods(router *mux.Router, request *http.Request) []string {
+	allowed := make(map[string]bool)
+	_ = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		var match mux.RouteMatch
+		if !route.Match(request, &match) && !errors.Is(match.MatchErr, mux.ErrMethodMismatch) {
+			return nil
+		}
+		routeMethods, methodsError := route.GetMethods()
+		if methodsError != nil {
+			return nil
+		}
+		for _, method := range routeMethods {
+			allowed[method] = true
+		}
+		return nil
+	})
+	methods := make([]string, 0, len(allowed))
+	for method := range allowed {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// ViewHandleRequests serves the API as configured until SIGINT or SIGTERM. See Server.
+func (controller SubscriberController) ViewHandleRequests() {
+	server, serverFail := makeServer(controller, settings().Server)
+	if serverFail != nil {
+		log.Fatal(serverFail)
+	}
+	if runFail := server.Run(); runFail != nil {
+		log.Fatal(runFail)
+	}
 }
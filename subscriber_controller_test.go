@@ -7,12 +7,14 @@
 package MarcGoRESTAPIDemo
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/gorilla/mux"
 	"log"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"strconv"
 	"testing"
 	"time"
@@ -24,7 +26,7 @@ func init() {
 
 type SubscriberControllerTestFixture struct {
 	dut             SubscriberController
-	model           Records
+	model           SubscriberStore
 	expectedRecords []Subscriber
 }
 
@@ -52,10 +54,10 @@ func setupSubscriberControllerTestFixture() SubscriberControllerTestFixture {
 			ActivationFlag: false,
 		},
 	}
-	model := MakeDatabaseRecords()
+	model := MakeMemoryRecords()
 	dut := MakeSubscriberController(model)
 	for _, subscriber := range expectedRecords {
-		_, createError := model.create(subscriber)
+		_, createError := model.Create(context.Background(), subscriber)
 		if createError != nil {
 			panic(createError.Error())
 		}
@@ -65,14 +67,7 @@ func setupSubscriberControllerTestFixture() SubscriberControllerTestFixture {
 
 func (fixture SubscriberControllerTestFixture) tearDown() {
 	fixture.expectedRecords = nil
-	_, truncateFail := fixture.model.database.Exec("truncate table `subscribers`")
-	if truncateFail != nil {
-		panic(truncateFail.Error())
-	}
-	dbCloseFail := fixture.model.database.Close()
-	if dbCloseFail != nil {
-		panic(dbCloseFail.Error())
-	}
+	fixture.model = nil
 }
 
 func TestListController(t *testing.T) {
@@ -88,9 +83,9 @@ func TestListController(t *testing.T) {
 		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
 	}
 	expectedMessage := ConvertToJson(fixture.expectedRecords)
-	if response.Body.String() != expectedMessage {
+	if subscribersOf(response.Body.String()) != expectedMessage {
 		t.Errorf("handler returned unexpected body: got %v want %v",
-			response.Body.String(), expectedMessage)
+			subscribersOf(response.Body.String()), expectedMessage)
 	}
 	fixture.tearDown()
 }
@@ -114,11 +109,15 @@ func TestCreateController(t *testing.T) {
 	createResponse := httptest.NewRecorder()
 	createHandler := http.HandlerFunc(fixture.dut.create)
 	createHandler.ServeHTTP(createResponse, request)
-	if status := createResponse.Code; status != http.StatusOK {
-		t.Errorf("createHandler returned wrong status code: got %v want %v", status, http.StatusOK)
+	if status := createResponse.Code; status != http.StatusCreated {
+		t.Errorf("createHandler returned wrong status code: got %v want %v", status, http.StatusCreated)
+	}
+	if location := createResponse.Header().Get("Location"); "/subscribers/4" != location {
+		t.Errorf("createHandler returned wrong Location: got %v want %v", location, "/subscribers/4")
 	}
+	subscriberForm.Index = 4
 	recordCreatedMessage := ConvertToJson(Update{"Record created", subscriberForm})
-	if createResponse.Body.String() != recordCreatedMessage {
+	if unstampedJson(createResponse.Body.String()) != recordCreatedMessage {
 		t.Errorf("createHandler returned unexpected body: got %v want %v", createResponse.Body.String(), recordCreatedMessage)
 	}
 
@@ -137,8 +136,85 @@ func TestCreateController(t *testing.T) {
 	if status := listResponse.Code; status != http.StatusOK {
 		t.Errorf("createHandler returned wrong status code: got %v want %v", status, http.StatusOK)
 	}
-	if listResponse.Body.String() != expectedMessage {
-		t.Errorf("createHandler returned unexpected body: got %v want %v", listResponse.Body.String(), expectedMessage)
+	if subscribersOf(listResponse.Body.String()) != expectedMessage {
+		t.Errorf("createHandler returned unexpected body: got %v want %v", subscribersOf(listResponse.Body.String()), expectedMessage)
+	}
+	fixture.tearDown()
+}
+
+func TestCreateActivatedController(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	request, fault := http.NewRequest("POST",
+		"/subscribers?email_address=riseofskywalker@starwars.com&first_name=Rey&activation_flag=true", nil)
+	if fault != nil {
+		t.Fatal(fault)
+	}
+	response := httptest.NewRecorder()
+	http.HandlerFunc(fixture.dut.create).ServeHTTP(response, request)
+	if status := response.Code; status != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+	}
+	expectedMessage := ConvertToJson(Update{"Record created",
+		Subscriber{Index: 4, EmailAddress: "riseofskywalker@starwars.com", FirstName: "Rey", ActivationFlag: true}})
+	if unstampedJson(response.Body.String()) != expectedMessage {
+		t.Errorf("handler returned unexpected body: got %v want %v", response.Body.String(), expectedMessage)
+	}
+	subscriber, retrieveFail := fixture.model.Retrieve(context.Background(), 4)
+	if retrieveFail != nil {
+		t.Fatal(retrieveFail)
+	}
+	if !subscriber.ActivationFlag {
+		t.Errorf("handler did not activate the new subscriber %v", *subscriber)
+	}
+
+	request, fault = http.NewRequest("POST", "/subscribers?email_address=kylo@starwars.com&activation_flag=maybe", nil)
+	if fault != nil {
+		t.Fatal(fault)
+	}
+	response = httptest.NewRecorder()
+	http.HandlerFunc(fixture.dut.create).ServeHTTP(response, request)
+	if status := response.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+	fixture.tearDown()
+}
+
+func TestSwapEmailAddressController(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	request, fault := http.NewRequest("POST", "/subscribers/1/swap_email_address?with=2", nil)
+	if fault != nil {
+		t.Fatal(fault)
+	}
+	request = mux.SetURLVars(request, map[string]string{"index": "1"})
+	response := httptest.NewRecorder()
+	http.HandlerFunc(fixture.dut.swapEmailAddress).ServeHTTP(response, request)
+	if status := response.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	expectedMessage := ConvertToJson(Message{"success", "Swapped email addresses of subscribers #1 and #2."})
+	if unstampedJson(response.Body.String()) != expectedMessage {
+		t.Errorf("handler returned unexpected body: got %v want %v", response.Body.String(), expectedMessage)
+	}
+	subscriber, retrieveFail := fixture.model.Retrieve(context.Background(), 1)
+	if retrieveFail != nil {
+		t.Fatal(retrieveFail)
+	}
+	if fixture.expectedRecords[1].EmailAddress != subscriber.EmailAddress {
+		t.Errorf("handler did not swap email addresses: got %v", subscriber.EmailAddress)
+	}
+
+	for rawQuery, expectedStatus := range map[string]int{"with=9": http.StatusNotFound, "with=1": http.StatusBadRequest,
+		"": http.StatusBadRequest} {
+		request, fault = http.NewRequest("POST", "/subscribers/1/swap_email_address?"+rawQuery, nil)
+		if fault != nil {
+			t.Fatal(fault)
+		}
+		request = mux.SetURLVars(request, map[string]string{"index": "1"})
+		response = httptest.NewRecorder()
+		http.HandlerFunc(fixture.dut.swapEmailAddress).ServeHTTP(response, request)
+		if status := response.Code; status != expectedStatus {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v", rawQuery, status, expectedStatus)
+		}
 	}
 	fixture.tearDown()
 }
@@ -161,7 +237,7 @@ func TestRetrieveController(t *testing.T) {
 		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
 	}
 	expectedMessage := ConvertToJson(fixture.expectedRecords[index-1])
-	if response.Body.String() != expectedMessage {
+	if unstampedJson(response.Body.String()) != expectedMessage {
 		t.Errorf("createHandler returned unexpected body: got %v want %v",
 			response.Body.String(), expectedMessage)
 	}
@@ -175,7 +251,7 @@ func TestUpdateController(t *testing.T) {
 		t.Fatal(fault)
 	}
 	form := Subscriber{}
-	form.Index = uint8(rand.Intn(len(fixture.expectedRecords)) + 1)
+	form.Index = uint64(rand.Intn(len(fixture.expectedRecords)) + 1)
 	form.FirstName = "Handsome Marc"
 	form.LastName = "Immaculate Conception"
 	vars := map[string]string{
@@ -208,9 +284,45 @@ func TestUpdateController(t *testing.T) {
 	fixture.expectedRecords[form.Index-1].FirstName = form.FirstName
 	fixture.expectedRecords[form.Index-1].LastName = form.LastName
 	expectedMessage := ConvertToJson(fixture.expectedRecords)
-	if listResponse.Body.String() != expectedMessage {
+	if subscribersOf(listResponse.Body.String()) != expectedMessage {
 		t.Errorf("handler returned unexpected body: got %v want %v",
-			listResponse.Body.String(), expectedMessage)
+			subscribersOf(listResponse.Body.String()), expectedMessage)
+	}
+	fixture.tearDown()
+}
+
+func TestUpdateControllerClearsAndRejectsFields(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	request, fault := http.NewRequest("PUT", "/subscribers/1?first_name=&last_name=Skywalker", nil)
+	if fault != nil {
+		t.Fatal(fault)
+	}
+	request = mux.SetURLVars(request, map[string]string{"index": "1"})
+	updateResponse := httptest.NewRecorder()
+	http.HandlerFunc(fixture.dut.update).ServeHTTP(updateResponse, request)
+	if status := updateResponse.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	subscriber, retrieveFail := fixture.model.Retrieve(context.Background(), 1)
+	if retrieveFail != nil {
+		t.Fatal(retrieveFail)
+	}
+	if "" != subscriber.FirstName || "Skywalker" != subscriber.LastName ||
+		fixture.expectedRecords[0].EmailAddress != subscriber.EmailAddress {
+		t.Errorf("handler updated unexpected fields: got %v", *subscriber)
+	}
+
+	for _, rawQuery := range []string{"activation_flag=true", "email_address=", "last_name=Skywalker&index=2"} {
+		request, fault = http.NewRequest("PUT", "/subscribers/1?"+rawQuery, nil)
+		if fault != nil {
+			t.Fatal(fault)
+		}
+		request = mux.SetURLVars(request, map[string]string{"index": "1"})
+		rejectResponse := httptest.NewRecorder()
+		http.HandlerFunc(fixture.dut.update).ServeHTTP(rejectResponse, request)
+		if status := rejectResponse.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v", rawQuery, status, http.StatusBadRequest)
+		}
 	}
 	fixture.tearDown()
 }
@@ -229,13 +341,11 @@ func TestDeleteController(t *testing.T) {
 	deleteResponse := httptest.NewRecorder()
 	handler := http.HandlerFunc(fixture.dut.delete)
 	handler.ServeHTTP(deleteResponse, request)
-	if status := deleteResponse.Code; status != http.StatusOK {
-		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	if status := deleteResponse.Code; status != http.StatusNoContent {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNoContent)
 	}
-	recordDeletedMessage := ConvertToJson(Message{"success", "Deleted record of subscriber #" + strconv.Itoa(index)})
-	if deleteResponse.Body.String() != recordDeletedMessage {
-		t.Errorf("handler returned unexpected body: got %v want %v",
-			deleteResponse.Body.String(), recordDeletedMessage)
+	if 0 != deleteResponse.Body.Len() {
+		t.Errorf("handler returned unexpected body: got %v want nothing", deleteResponse.Body.String())
 	}
 
 	listResponse := httptest.NewRecorder()
@@ -249,8 +359,8 @@ func TestDeleteController(t *testing.T) {
 		updatedExpectedRecords = append(fixture.expectedRecords[:index-1], fixture.expectedRecords[index-1+1:]...)
 	}
 	expectedMessage := ConvertToJson(updatedExpectedRecords)
-	if listResponse.Body.String() != expectedMessage {
-		t.Errorf("handler returned unexpected body: got %v want %v", listResponse.Body.String(), expectedMessage)
+	if subscribersOf(listResponse.Body.String()) != expectedMessage {
+		t.Errorf("handler returned unexpected body: got %v want %v", subscribersOf(listResponse.Body.String()), expectedMessage)
 	}
 	fixture.tearDown()
 }
@@ -280,16 +390,100 @@ func TestActivateController(t *testing.T) {
 		t.Errorf("handler returned unexpected body: got %v want %v", activateResponse.Body.String(), expectedResponse)
 	}
 
+	listRequest, fault := http.NewRequest("GET", "/subscribers", nil)
+	if fault != nil {
+		t.Fatal(fault)
+	}
 	listHandler := http.HandlerFunc(fixture.dut.list)
 	listResponse := httptest.NewRecorder()
-	listHandler.ServeHTTP(listResponse, request)
+	listHandler.ServeHTTP(listResponse, listRequest)
 	if status := listResponse.Code; status != http.StatusOK {
 		t.Errorf("createHandler returned wrong status code: got %v want %v", status, http.StatusOK)
 	}
 	fixture.expectedRecords[index-1].ActivationFlag = true
 	expectedMessage := ConvertToJson(fixture.expectedRecords)
-	if listResponse.Body.String() != expectedMessage {
-		t.Errorf("handler returned unexpected body: got %v want %v", listResponse.Body.String(), expectedMessage)
+	if subscribersOf(listResponse.Body.String()) != expectedMessage {
+		t.Errorf("handler returned unexpected body: got %v want %v", subscribersOf(listResponse.Body.String()), expectedMessage)
+	}
+	fixture.tearDown()
+}
+
+func TestControllerTimeouts(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	expiredContext, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	request, fault := http.NewRequestWithContext(expiredContext, "GET", "/subscribers", nil)
+	if fault != nil {
+		t.Fatal(fault)
+	}
+	response := httptest.NewRecorder()
+	http.HandlerFunc(fixture.dut.list).ServeHTTP(response, request)
+	if status := response.Code; status != http.StatusGatewayTimeout {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusGatewayTimeout)
+	}
+
+	canceledContext, cancel := context.WithCancel(context.Background())
+	cancel()
+	request, fault = http.NewRequestWithContext(canceledContext, "GET", "/subscribers", nil)
+	if fault != nil {
+		t.Fatal(fault)
+	}
+	request = mux.SetURLVars(request, map[string]string{"index": "1"})
+	response = httptest.NewRecorder()
+	http.HandlerFunc(fixture.dut.retrieve).ServeHTTP(response, request)
+	if status := response.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusServiceUnavailable)
+	}
+	fixture.tearDown()
+}
+
+func subscribersOf(pageBody string) string {
+	var page Page
+	if jsonError := json.Unmarshal([]byte(pageBody), &page); jsonError != nil {
+		return pageBody
+	}
+	return ConvertToJson(unstampedList(page.Subscribers))
+}
+
+var stampedFields = regexp.MustCompile(`,"(id|created_at|updated_at)":"[^"]*"`)
+
+// unstampedJson leaves out the public IDs and the timestamps of the subscribers in a response body.
+func unstampedJson(body string) string {
+	return stampedFields.ReplaceAllString(body, "")
+}
+
+func TestControllerStatusCodes(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	for _, testCase := range []struct {
+		handler        http.HandlerFunc
+		index          string
+		target         string
+		expectedStatus int
+	}{
+		{fixture.dut.create, "", "/subscribers", http.StatusBadRequest},
+		{fixture.dut.create, "", "/subscribers?first_name=Rey", http.StatusBadRequest},
+		{fixture.dut.create, "", "/subscribers?email_address=kevin.andrews%40email.com", http.StatusConflict},
+		{fixture.dut.update, "1", "/subscribers/1", http.StatusBadRequest},
+		{fixture.dut.update, "1", "/subscribers/1?email_address=kevin.andrews%40email.com", http.StatusConflict},
+		{fixture.dut.update, "200", "/subscribers/200?first_name=Nobody", http.StatusNotFound},
+		{fixture.dut.update, "2", "/subscribers/2?first_name=Marc", http.StatusOK},
+		{fixture.dut.activate, "200", "/subscribers/200?activation_flag=true", http.StatusNotFound},
+		{fixture.dut.delete, "200", "/subscribers/200", http.StatusNotFound},
+		{fixture.dut.delete, "abc", "/subscribers/abc", http.StatusBadRequest},
+	} {
+		request, fault := http.NewRequest("POST", testCase.target, nil)
+		if fault != nil {
+			t.Fatal(fault)
+		}
+		if "" != testCase.index {
+			request = mux.SetURLVars(request, map[string]string{"index": testCase.index})
+		}
+		response := httptest.NewRecorder()
+		testCase.handler.ServeHTTP(response, request)
+		if status := response.Code; status != testCase.expectedStatus {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v", testCase.target, status,
+				testCase.expectedStatus)
+		}
 	}
 	fixture.tearDown()
 }
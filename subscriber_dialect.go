@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"github.com/go-sql-driver/mysql"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Statements in the model are written with `backtick` quoted identifiers and ? placeholders.
+// A dialect renders them into the syntax of its driver.
+type dialect interface {
+	driver() string
+	dataSourceName(configuration DatabaseConfiguration) string
+	quote(identifier string) string
+	placeholder(position int) string
+	// truncate empties a table, cascading to the tables that reference it, and restarts its index.
+	truncate(table string) []string
+	returning(column string) string
+	// lockRows locks the selected rows until the end of the transaction, where the database needs to be told.
+	lockRows() string
+}
+
+var dialects = map[string]dialect{"": mysqlDialect{}, "mysql": mysqlDialect{}, "sqlite3": sqliteDialect{},
+	"postgres": postgresDialect{}}
+
+func dialectOf(driver string) dialect {
+	dialect, supported := dialects[driver]
+	if !supported {
+		panic("Database driver " + driver + " is not supported.")
+	}
+	return dialect
+}
+
+func render(dialect dialect, statement string) string {
+	var rendered strings.Builder
+	position := 0
+	for cursor := 0; cursor < len(statement); cursor++ {
+		switch statement[cursor] {
+		case '`':
+			closing := strings.IndexByte(statement[cursor+1:], '`')
+			if closing < 0 {
+				panic("Unterminated identifier in statement " + statement)
+			}
+			rendered.WriteString(dialect.quote(statement[cursor+1 : cursor+1+closing]))
+			cursor += closing + 1
+		case '?':
+			position++
+			rendered.WriteString(dialect.placeholder(position))
+		default:
+			rendered.WriteByte(statement[cursor])
+		}
+	}
+	return rendered.String()
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) driver() string {
+	return "mysql"
+}
+
+func (mysqlDialect) dataSourceName(configuration DatabaseConfiguration) string {
+	dsn := mysql.NewConfig()
+	dsn.User = configuration.User
+	dsn.Passwd = configuration.Password
+	dsn.Net = "tcp"
+	dsn.Addr = net.JoinHostPort(configuration.Host, strconv.Itoa(int(configuration.Port)))
+	dsn.DBName = configuration.DBName
+	dsn.ParseTime = true
+	dsn.ClientFoundRows = true
+	return dsn.FormatDSN()
+}
+
+func (mysqlDialect) quote(identifier string) string {
+	return "`" + identifier + "`"
+}
+
+func (mysqlDialect) placeholder(int) string {
+	return "?"
+}
+
+func (mysqlDialect) truncate(table string) []string {
+	return []string{"delete from `" + table + "`", "alter table `" + table + "` auto_increment = 1"}
+}
+
+func (mysqlDialect) returning(string) string {
+	return ""
+}
+
+func (mysqlDialect) lockRows() string {
+	return " for update"
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) driver() string {
+	return "sqlite3"
+}
+
+func (sqliteDialect) dataSourceName(configuration DatabaseConfiguration) string {
+	return configuration.DBName + "?_busy_timeout=5000&_foreign_keys=on"
+}
+
+func (sqliteDialect) quote(identifier string) string {
+	return `"` + identifier + `"`
+}
+
+func (sqliteDialect) placeholder(int) string {
+	return "?"
+}
+
+func (sqliteDialect) truncate(table string) []string {
+	return []string{"delete from `" + table + "`", "delete from `sqlite_sequence` where `name` = '" + table + "'"}
+}
+
+func (sqliteDialect) returning(string) string {
+	return ""
+}
+
+// SQLite locks the whole database for a writing transaction, and the records use a single connection.
+func (sqliteDialect) lockRows() string {
+	return ""
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) driver() string {
+	return "postgres"
+}
+
+func (postgresDialect) dataSourceName(configuration DatabaseConfiguration) string {
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(configuration.User, configuration.Password),
+		Host:   net.JoinHostPort(configuration.Host, strconv.Itoa(int(configuration.Port))),
+		Path:   "/" + configuration.DBName,
+	}
+	if "" != configuration.SSLMode {
+		dsn.RawQuery = url.Values{"sslmode": {configuration.SSLMode}}.Encode()
+	}
+	return dsn.String()
+}
+
+func (postgresDialect) quote(identifier string) string {
+	return `"` + identifier + `"`
+}
+
+func (postgresDialect) placeholder(position int) string {
+	return "$" + strconv.Itoa(position)
+}
+
+func (postgresDialect) truncate(table string) []string {
+	return []string{"truncate table `" + table + "` restart identity cascade"}
+}
+
+func (postgresDialect) returning(column string) string {
+	return " returning `" + column + "`"
+}
+
+func (postgresDialect) lockRows() string {
+	return " for update"
+}
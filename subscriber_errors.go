@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"net"
+)
+
+// The stores report their failures with these errors, whatever the database. Use errors.Is to tell them apart;
+// the error of the database stays available through errors.Unwrap.
+var (
+	ErrNotFound       = errors.New("Subscriber does not exist.")
+	ErrDuplicateEmail = errors.New("Subscriber email_address is already taken.")
+	ErrConflict       = errors.New("The subscriber was changed by another request. Please try again.")
+	ErrUnavailable    = errors.New("The database is unavailable. Please try again later.")
+	// ErrDeletedEmail tells that the email_address is taken by a subscriber in the trash. It is also ErrDuplicateEmail.
+	ErrDeletedEmail = errors.New("Subscriber email_address belongs to a deleted subscriber.")
+	// ErrPreconditionFailed tells that a subscriber no longer has the version a change was meant for.
+	ErrPreconditionFailed = errors.New("The subscriber was changed since it was read. Please read it again.")
+)
+
+// ValidationError rejects a command before it reaches the database. Its message is meant for the client.
+// Field names the subscriber field or query parameter at fault, if any.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (fault ValidationError) Error() string {
+	return fault.Message
+}
+
+type storeError struct {
+	kind  error
+	cause error
+}
+
+func (fault storeError) Error() string {
+	return fault.kind.Error() + " " + fault.cause.Error()
+}
+
+func (fault storeError) Is(target error) bool {
+	return target == fault.kind
+}
+
+func (fault storeError) Unwrap() error {
+	return fault.cause
+}
+
+// deletedEmailError names the subscriber in the trash that keeps an email address taken, and how to restore it.
+type deletedEmailError struct {
+	restorePath string
+	cause       error
+}
+
+func (fault deletedEmailError) Error() string {
+	return ErrDeletedEmail.Error() + " Please restore it with POST " + fault.restorePath +
+		", or use the address again once it is purged."
+}
+
+func (fault deletedEmailError) Is(target error) bool {
+	return target == ErrDeletedEmail
+}
+
+func (fault deletedEmailError) Unwrap() error {
+	return fault.cause
+}
+
+// translateFault turns a database error into one of the store errors. Errors it does not know are left as they are.
+func translateFault(fault error) error {
+	var translated storeError
+	if fault == nil || errors.As(fault, &translated) {
+		return fault
+	}
+	switch {
+	case errors.Is(fault, sql.ErrNoRows):
+		return storeError{ErrNotFound, fault}
+	case isDuplicateEntry(fault):
+		return storeError{ErrDuplicateEmail, fault}
+	case isUnavailable(fault):
+		return storeError{ErrUnavailable, fault}
+	}
+	return fault
+}
+
+// isDuplicateEntry tells whether a store rejected a subscriber because its email_address is already taken.
+func isDuplicateEntry(fault error) bool {
+	var mysqlError *mysql.MySQLError
+	var sqliteError sqlite3.Error
+	var postgresError *pq.Error
+	var memoryError duplicateEntryError
+	switch {
+	case errors.As(fault, &mysqlError):
+		return 1062 == mysqlError.Number
+	case errors.As(fault, &sqliteError):
+		return sqlite3.ErrConstraintUnique == sqliteError.ExtendedCode
+	case errors.As(fault, &postgresError):
+		return "23505" == postgresError.Code
+	}
+	return errors.As(fault, &memoryError)
+}
+
+// isUnavailable tells whether the database could not be reached or was too busy to answer.
+func isUnavailable(fault error) bool {
+	var sqliteError sqlite3.Error
+	var postgresError *pq.Error
+	var networkError net.Error
+	switch {
+	case errors.Is(fault, driver.ErrBadConn), errors.Is(fault, sql.ErrConnDone), errors.Is(fault, mysql.ErrInvalidConn):
+		return true
+	case errors.As(fault, &sqliteError):
+		return sqlite3.ErrBusy == sqliteError.Code || sqlite3.ErrLocked == sqliteError.Code
+	case errors.As(fault, &postgresError):
+		return "08" == postgresError.Code.Class() || "57P03" == postgresError.Code
+	}
+	return errors.As(fault, &networkError)
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"testing"
+)
+
+func TestTranslateFault(t *testing.T) {
+	unknown := errors.New("Error 1146: Table 'subscribers_database.subscribers' doesn't exist")
+	for _, testCase := range []struct {
+		fault    error
+		expected error
+	}{
+		{sql.ErrNoRows, ErrNotFound},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'rey@starwars.com' for key 'subscribers.email_address'"},
+			ErrDuplicateEmail},
+		{sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}, ErrDuplicateEmail},
+		{&pq.Error{Code: "23505"}, ErrDuplicateEmail},
+		{duplicateEntryError{"rey@starwars.com"}, ErrDuplicateEmail},
+		{fmt.Errorf("connecting: %w", driver.ErrBadConn), ErrUnavailable},
+		{mysql.ErrInvalidConn, ErrUnavailable},
+		{sqlite3.Error{Code: sqlite3.ErrBusy}, ErrUnavailable},
+		{&pq.Error{Code: "08006"}, ErrUnavailable},
+		{unknown, unknown},
+	} {
+		translated := translateFault(testCase.fault)
+		if !errors.Is(translated, testCase.expected) || !errors.Is(translated, testCase.fault) {
+			t.Errorf("ERROR %v was translated into %v, expected %v", testCase.fault, translated, testCase.expected)
+		}
+		if translated != translateFault(translated) {
+			t.Errorf("ERROR %v was translated twice.", translated)
+		}
+	}
+	if nil != translateFault(nil) {
+		t.Errorf("ERROR no error was translated into an error.")
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Subscriber indexes go from 1 to the largest signed 64-bit integer, which every database can auto-increment to.
+const maxIndex = math.MaxInt64
+
+var publicIDPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+func parseIndex(field string, value string) (uint64, error) {
+	index, indexError := strconv.ParseUint(value, 10, 63)
+	if indexError != nil || 0 == index {
+		return 0, ValidationError{field, "Subscriber " + field + " must be a number from 1 to " +
+			strconv.FormatUint(maxIndex, 10) + "."}
+	}
+	return index, nil
+}
+
+// newPublicID makes a random UUID (version 4). Public IDs are opaque, so they do not tell how many subscribers
+// there are.
+func newPublicID() string {
+	var uuid [16]byte
+	if _, randomError := rand.Read(uuid[:]); randomError != nil {
+		log.Panic(randomError)
+	}
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	digits := hex.EncodeToString(uuid[:])
+	return digits[:8] + "-" + digits[8:12] + "-" + digits[12:16] + "-" + digits[16:20] + "-" + digits[20:]
+}
+
+// parsePublicID reads a UUID in its canonical form, regardless of case.
+func parsePublicID(value string) (string, bool) {
+	value = strings.ToLower(value)
+	return value, publicIDPattern.MatchString(value)
+}
+
+// Resolve finds the index of the subscriber with a public ID, deleted or not.
+func (records Records) Resolve(ctx context.Context, publicID string) (uint64, error) {
+	ctx, cancel := records.withTimeout(ctx)
+	defer cancel()
+	var index uint64
+	resolveFail := records.queryRow(ctx, "select `index` from `subscribers` where `public_id`=?", publicID).Scan(&index)
+	return index, storeFault(ctx, resolveFail)
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseIndex(t *testing.T) {
+	for value, expected := range map[string]uint64{
+		"1":                    1,
+		"256":                  256,
+		"300":                  300,
+		"9223372036854775807":  maxIndex,
+		"0":                    0,
+		"-1":                   0,
+		"9223372036854775808":  0,
+		"18446744073709551616": 0,
+		"1e3":                  0,
+		"":                     0,
+	} {
+		index, indexError := parseIndex("index", value)
+		if expected != index || (0 == expected) != (indexError != nil) {
+			t.Errorf("ERROR parsing index %q returned %d, expected %d. %v", value, index, expected, indexError)
+		}
+	}
+}
+
+func TestPublicIDs(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		publicIDs := make(map[string]uint64)
+		for index := range fixture.expectedRecords {
+			subscriber, retrieveFail := fixture.dut.Retrieve(context.Background(), uint64(index+1))
+			if retrieveFail != nil {
+				t.Fatalf("ERROR retrieving database record. %s", retrieveFail.Error())
+			}
+			if _, isPublicID := parsePublicID(subscriber.PublicID); !isPublicID {
+				t.Errorf("ERROR subscriber #%d has no public ID: %q", subscriber.Index, subscriber.PublicID)
+			}
+			publicIDs[subscriber.PublicID] = subscriber.Index
+		}
+		if len(fixture.expectedRecords) != len(publicIDs) {
+			t.Errorf("ERROR subscribers share public IDs: %v", publicIDs)
+		}
+		if _, deleteFail := fixture.dut.Delete(context.Background(), 2); deleteFail != nil {
+			t.Fatal(deleteFail)
+		}
+		for publicID, expected := range publicIDs {
+			if index, resolveFail := fixture.dut.Resolve(context.Background(), publicID); resolveFail != nil || expected != index {
+				t.Errorf("ERROR resolving %s returned #%d, expected #%d. %v", publicID, index, expected, resolveFail)
+			}
+		}
+		if _, resolveFail := fixture.dut.Resolve(context.Background(), newPublicID()); !errors.Is(resolveFail, ErrNotFound) {
+			t.Errorf("ERROR expected %v when resolving an unknown public ID, got %v", ErrNotFound, resolveFail)
+		}
+	})
+}
+
+func TestControllerIndexes(t *testing.T) {
+	model := MakeMemoryRecords()
+	if _, createFail := model.CreateBatch(context.Background(), makeBulkSubscribers(300)); createFail != nil {
+		t.Fatal(createFail)
+	}
+	controller := makeSubscriberController(model, MVCConfiguration{PageSize: 10, MaxPageSize: 20})
+	for _, index := range []string{"44", "256", "300"} {
+		response := sendAccepting(controller.retrieve, "GET", "/subscribers/"+index, index, "")
+		var subscriber Subscriber
+		if jsonError := json.Unmarshal(response.Body.Bytes(), &subscriber); jsonError != nil ||
+			index != strconv.FormatUint(subscriber.Index, 10) {
+			t.Errorf("handler returned the wrong subscriber for #%s: %s", index, response.Body.String())
+		}
+	}
+	for index, expectedStatus := range map[string]int{"0": http.StatusBadRequest, "9223372036854775808": http.StatusBadRequest,
+		"-1": http.StatusBadRequest, "9223372036854775807": http.StatusNotFound,
+		"00000000-0000-4000-8000-000000000000": http.StatusNotFound} {
+		response := sendAccepting(controller.retrieve, "GET", "/subscribers/"+index, index, "")
+		if status := response.Code; status != expectedStatus {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v", index, status, expectedStatus)
+		}
+	}
+	_, page := listPage(t, controller, "/subscribers?cursor="+encodeCursor("after", 295))
+	if 5 != len(page.Subscribers) || 296 != page.Subscribers[0].Index {
+		t.Errorf("handler returned unexpected page after #295: %v", page.Subscribers)
+	}
+
+	subscriber, _ := model.Retrieve(context.Background(), 256)
+	response := sendAccepting(controller.retrieve, "GET", "/subscribers/"+subscriber.PublicID,
+		strings.ToUpper(subscriber.PublicID), "")
+	if unstampedJson(response.Body.String()) != ConvertToJson(unstamped(*subscriber)) {
+		t.Errorf("handler returned the wrong subscriber for %s: %s", subscriber.PublicID, response.Body.String())
+	}
+	response = sendBody(controller.patch, "PATCH", "/subscribers/256", "256", mergePatchMediaType,
+		`{"index": 256, "id": "`+subscriber.PublicID+`", "first_name": "Patched"}`)
+	if status := response.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v. %s", status, http.StatusOK, response.Body.String())
+	}
+	response = sendBody(controller.patch, "PATCH", "/subscribers/256", "256", mergePatchMediaType, `{"id": "`+newPublicID()+`"}`)
+	if status := response.Code; status != http.StatusUnprocessableEntity {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestControllerPublicIDsOnly(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	controller := makeSubscriberController(fixture.model, MVCConfiguration{PageSize: 10, MaxPageSize: 20, PublicIDs: true})
+	first, _ := fixture.model.Retrieve(context.Background(), 1)
+	second, _ := fixture.model.Retrieve(context.Background(), 2)
+
+	if response := sendAccepting(controller.retrieve, "GET", "/subscribers/2", "2", ""); http.StatusNotFound != response.Code {
+		t.Errorf("handler returned wrong status code for an index: got %v want %v", response.Code, http.StatusNotFound)
+	}
+	response := sendAccepting(controller.retrieve, "GET", "/subscribers/"+second.PublicID, second.PublicID, "")
+	expected := *second
+	expected.Index = 0
+	var subscriber Subscriber
+	if jsonError := json.Unmarshal(response.Body.Bytes(), &subscriber); jsonError != nil || http.StatusOK != response.Code ||
+		ConvertToJson(expected) != ConvertToJson(subscriber) || strings.Contains(response.Body.String(), `"index"`) {
+		t.Errorf("handler returned unexpected response: got %v %s want %s", response.Code, response.Body.String(),
+			ConvertToJson(expected))
+	}
+	response, page := listPage(t, controller, "/subscribers")
+	if 3 != len(page.Subscribers) || strings.Contains(response.Body.String(), `"index"`) {
+		t.Errorf("handler returned indexes: %s", response.Body.String())
+	}
+	response, page = listPage(t, controller, "/subscribers?limit=1&cursor="+encodeCursorKey("after", first.PublicID))
+	if 1 != len(page.Subscribers) || second.PublicID != page.Subscribers[0].PublicID {
+		t.Fatalf("handler returned unexpected cursor page: %s", response.Body.String())
+	}
+	for _, link := range []string{page.NextCursor, page.PrevCursor, page.Links.Next, page.Links.Prev,
+		response.Header().Get("Link")} {
+		for _, cursor := range regexp.MustCompile(`[A-Za-z0-9_-]{8,}`).FindAllString(link, -1) {
+			if decoded, decodeError := base64.RawURLEncoding.DecodeString(cursor); decodeError == nil &&
+				regexp.MustCompile(`:[0-9]+$`).Match(decoded) {
+				t.Errorf("ERROR the link %s exposes an index: %s", link, decoded)
+			}
+		}
+	}
+	if _, nextPage := listPage(t, controller, page.Links.Next); 1 != len(nextPage.Subscribers) ||
+		3 != len(page.Subscribers)+len(nextPage.Subscribers)+1 {
+		t.Errorf("handler returned unexpected next page: %v", nextPage.Subscribers)
+	}
+	if _, previousPage := listPage(t, controller, page.Links.Prev); 1 != len(previousPage.Subscribers) ||
+		first.PublicID != previousPage.Subscribers[0].PublicID {
+		t.Errorf("handler returned unexpected previous page: %v", previousPage.Subscribers)
+	}
+	for _, cursor := range []string{encodeCursor("after", 1), encodeCursorKey("after", newPublicID())} {
+		if response, _ := listPage(t, controller, "/subscribers?cursor="+cursor); http.StatusBadRequest != response.Code {
+			t.Errorf("handler returned wrong status code for cursor %s: got %v want %v", cursor, response.Code,
+				http.StatusBadRequest)
+		}
+	}
+
+	response = sendBody(controller.create, "POST", "/subscribers", "", "application/json",
+		`{"email_address": "riseofskywalker@starwars.com"}`)
+	var created Update
+	if jsonError := json.Unmarshal(response.Body.Bytes(), &created); jsonError != nil ||
+		"/subscribers/"+created.Updates.PublicID != response.Header().Get("Location") || 0 != created.Updates.Index {
+		t.Errorf("handler returned unexpected response: got %v %s", response.Header(), response.Body.String())
+	}
+
+	response = sendBody(controller.swapEmailAddress, "POST", "/subscribers/"+first.PublicID+"/swap_email_address?with="+
+		second.PublicID, first.PublicID, "", "")
+	expectedMessage := ConvertToJson(Message{"success", "Swapped email addresses of subscribers " + first.PublicID +
+		" and " + second.PublicID + "."})
+	if status := response.Code; status != http.StatusOK || expectedMessage != response.Body.String() {
+		t.Errorf("handler returned unexpected response: got %v %s want %s", status, response.Body.String(), expectedMessage)
+	}
+	fixture.tearDown()
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	SubscriberPending     = "pending"
+	SubscriberActive      = "active"
+	SubscriberDeactivated = "deactivated"
+	SubscriberBounced     = "bounced"
+)
+
+// subscriberTransitions lists the statuses a subscriber can move to from each status.
+// Only active subscribers have their activation_flag set.
+var subscriberTransitions = map[string][]string{
+	SubscriberPending:     {SubscriberActive, SubscriberDeactivated, SubscriberBounced},
+	SubscriberActive:      {SubscriberDeactivated, SubscriberBounced},
+	SubscriberDeactivated: {SubscriberActive},
+	SubscriberBounced:     {SubscriberPending, SubscriberDeactivated},
+}
+
+type SubscriberTransition struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+type SubscriberLifecycle struct {
+	Index       uint64                 `json:"index,omitempty"`
+	Status      string                 `json:"status"`
+	Transitions []SubscriberTransition `json:"transitions"`
+}
+
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (fault TransitionError) Error() string {
+	return "A " + fault.From + " subscriber cannot become " + fault.To + ". It can only become " +
+		strings.Join(subscriberTransitions[fault.From], ", ") + "."
+}
+
+// A transition that is not allowed conflicts with the current status of the subscriber.
+func (fault TransitionError) Is(target error) bool {
+	return ErrConflict == target
+}
+
+func validStatus(status string) bool {
+	_, found := subscriberTransitions[status]
+	return found
+}
+
+func checkTransition(from string, to string) error {
+	if !validStatus(to) {
+		return ValidationError{"status", "Subscriber status " + to + " does not exist. Statuses are " +
+			strings.Join([]string{SubscriberPending, SubscriberActive, SubscriberDeactivated, SubscriberBounced}, ", ") + "."}
+	}
+	if from == to {
+		return nil
+	}
+	for _, allowed := range subscriberTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return TransitionError{from, to}
+}
+
+func activationStatus(activate bool) string {
+	if activate {
+		return SubscriberActive
+	}
+	return SubscriberDeactivated
+}
+
+// Transition moves a subscriber to status and records when it did. Moving to the current status changes nothing.
+// A missing subscriber affects no rows.
+func (records Records) Transition(ctx context.Context, index uint64, status string) (sql.Result, error) {
+	var result sql.Result = storeResult{}
+	transactionFail := records.Transaction(ctx, func(store SubscriberStore) error {
+		transaction := store.(Records)
+		ctx, cancel := transaction.withTimeout(ctx)
+		defer cancel()
+		var current string
+		currentFail := transaction.queryRow(ctx,
+			"select `status` from `subscribers` where `index`=? and `deleted_at` is null", index).Scan(&current)
+		if errors.Is(currentFail, sql.ErrNoRows) {
+			return nil
+		}
+		if currentFail != nil {
+			return storeFault(ctx, currentFail)
+		}
+		if transitionError := checkTransition(current, status); transitionError != nil {
+			return transitionError
+		}
+		if current == status {
+			result = storeResult{0, 1}
+			return nil
+		}
+		now := storeTime()
+		activationFlag := 0
+		if SubscriberActive == status {
+			activationFlag = 1
+		}
+		updated, updateFail := transaction.exec(ctx, "update `subscribers` set `status`=?, `activation_flag`=?, `version`=`version`+1, "+
+			"`updated_at`=? where `index`=? and `status`=? and `deleted_at` is null", status, activationFlag, now, index, current)
+		if updateFail != nil {
+			return updateFail
+		}
+		if rowsAffected, _ := updated.RowsAffected(); 0 == rowsAffected {
+			return ErrConflict
+		}
+		if _, insertFail := transaction.exec(ctx, "insert into `subscriber_transitions` "+
+			"(`index`, `from_status`, `to_status`, `transitioned_at`) values (?, ?, ?, ?)",
+			index, current, status, now); insertFail != nil {
+			return insertFail
+		}
+		result = storeResult{0, 1}
+		return nil
+	})
+	return result, transactionFail
+}
+
+func (records Records) Lifecycle(ctx context.Context, index uint64) (*SubscriberLifecycle, error) {
+	ctx, cancel := records.withTimeout(ctx)
+	defer cancel()
+	lifecycle := SubscriberLifecycle{Index: index, Transitions: make([]SubscriberTransition, 0)}
+	statusFail := records.queryRow(ctx, "select `status` from `subscribers` where `index`=? and `deleted_at` is null",
+		index).Scan(&lifecycle.Status)
+	if statusFail != nil {
+		return &lifecycle, storeFault(ctx, statusFail)
+	}
+	rows, dbQueryError := records.query(ctx, "select `from_status`, `to_status`, `transitioned_at` "+
+		"from `subscriber_transitions` where `index`=? order by `id`", index)
+	if dbQueryError != nil {
+		return &lifecycle, dbQueryError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var transition SubscriberTransition
+		if scanFail := rows.Scan(&transition.From, &transition.To, &transition.At); scanFail != nil {
+			return &lifecycle, storeFault(ctx, scanFail)
+		}
+		transition.At = transition.At.UTC()
+		lifecycle.Transitions = append(lifecycle.Transitions, transition)
+	}
+	return &lifecycle, storeFault(ctx, rows.Err())
+}
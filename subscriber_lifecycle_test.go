@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestSubscriberTransitions(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		ctx := context.Background()
+		lifecycle, lifecycleFail := fixture.dut.Lifecycle(ctx, 1)
+		if lifecycleFail != nil || SubscriberPending != lifecycle.Status || 0 != len(lifecycle.Transitions) {
+			t.Fatalf("ERROR expected a pending subscriber without transitions, got %v. %v", lifecycle, lifecycleFail)
+		}
+		for _, step := range []struct {
+			status   string
+			legal    bool
+			activate bool
+		}{
+			{SubscriberActive, true, true},
+			{SubscriberActive, true, true},
+			{SubscriberPending, false, true},
+			{SubscriberBounced, true, false},
+			{SubscriberActive, false, false},
+			{SubscriberPending, true, false},
+		} {
+			result, transitionFail := fixture.dut.Transition(ctx, 1, step.status)
+			var transitionError TransitionError
+			if step.legal && transitionFail != nil {
+				t.Fatalf("ERROR moving subscriber to %s. %s", step.status, transitionFail.Error())
+			}
+			if !step.legal && !errors.As(transitionFail, &transitionError) {
+				t.Fatalf("ERROR expected a transition error when moving subscriber to %s, got %v", step.status, transitionFail)
+			}
+			if step.legal {
+				if rowsAffected, _ := result.RowsAffected(); 1 != rowsAffected {
+					t.Errorf("ERROR moving subscriber to %s affected %d rows.", step.status, rowsAffected)
+				}
+			}
+			if subscriber, _ := fixture.dut.Retrieve(ctx, 1); step.activate != subscriber.ActivationFlag {
+				t.Errorf("ERROR subscriber %v does not have the activation flag %v.", *subscriber, step.activate)
+			}
+		}
+		if _, activateFail := fixture.dut.Activate(ctx, 1, false); activateFail != nil {
+			t.Errorf("ERROR deactivating a pending subscriber. %s", activateFail.Error())
+		}
+
+		lifecycle, lifecycleFail = fixture.dut.Lifecycle(ctx, 1)
+		if lifecycleFail != nil {
+			t.Fatalf("ERROR reading the subscriber lifecycle. %s", lifecycleFail.Error())
+		}
+		expectedTransitions := []SubscriberTransition{
+			{From: SubscriberPending, To: SubscriberActive},
+			{From: SubscriberActive, To: SubscriberBounced},
+			{From: SubscriberBounced, To: SubscriberPending},
+			{From: SubscriberPending, To: SubscriberDeactivated},
+		}
+		if SubscriberDeactivated != lifecycle.Status || len(expectedTransitions) != len(lifecycle.Transitions) {
+			t.Fatalf("ERROR unexpected lifecycle %v", lifecycle)
+		}
+		for position, expected := range expectedTransitions {
+			transition := lifecycle.Transitions[position]
+			if expected.From != transition.From || expected.To != transition.To || transition.At.IsZero() ||
+				(position > 0 && transition.At.Before(lifecycle.Transitions[position-1].At)) {
+				t.Errorf("ERROR unexpected transition %v, expected %v", transition, expected)
+			}
+		}
+
+		if result, _ := fixture.dut.Transition(ctx, 200, SubscriberActive); nil != result {
+			if rowsAffected, _ := result.RowsAffected(); 0 != rowsAffected {
+				t.Errorf("ERROR moving a missing subscriber affected %d rows.", rowsAffected)
+			}
+		}
+		if _, lifecycleFail = fixture.dut.Lifecycle(ctx, 200); !errors.Is(lifecycleFail, sql.ErrNoRows) {
+			t.Errorf("ERROR expected %v for the lifecycle of a missing subscriber, got %v", sql.ErrNoRows, lifecycleFail)
+		}
+		if _, transitionFail := fixture.dut.Transition(ctx, 2, "archived"); transitionFail == nil {
+			t.Errorf("ERROR an unknown status was accepted.")
+		}
+		if _, deleteFail := fixture.dut.Delete(ctx, 1); deleteFail != nil {
+			t.Errorf("ERROR deleting a subscriber with transitions. %s", deleteFail.Error())
+		}
+	})
+}
+
+func TestTransitionController(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	response := sendBody(fixture.dut.transition, "PUT", "/subscribers/1/status", "1", "application/json",
+		`{"status": "active"}`)
+	var lifecycle SubscriberLifecycle
+	if jsonError := json.Unmarshal(response.Body.Bytes(), &lifecycle); jsonError != nil || http.StatusOK != response.Code ||
+		SubscriberActive != lifecycle.Status || 1 != len(lifecycle.Transitions) {
+		t.Errorf("handler returned unexpected response: got %v %s", response.Code, response.Body.String())
+	}
+	if subscriber, _ := fixture.model.Retrieve(context.Background(), 1); !subscriber.ActivationFlag {
+		t.Errorf("ERROR an active subscriber %v is not activated.", *subscriber)
+	}
+	response = sendBody(fixture.dut.activate, "PATCH", "/subscribers/1?activation_flag=false", "1", "", "")
+	if status := response.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	response = sendBody(fixture.dut.retrieveStatus, "GET", "/subscribers/1/status", "1", "", "")
+	if jsonError := json.Unmarshal(response.Body.Bytes(), &lifecycle); jsonError != nil ||
+		SubscriberDeactivated != lifecycle.Status || 2 != len(lifecycle.Transitions) {
+		t.Errorf("handler returned unexpected lifecycle: %s", response.Body.String())
+	}
+	for _, testCase := range []struct {
+		handler        http.HandlerFunc
+		index          string
+		target         string
+		expectedStatus int
+	}{
+		{fixture.dut.transition, "1", "/subscribers/1/status?status=pending", http.StatusConflict},
+		{fixture.dut.transition, "1", "/subscribers/1/status?status=archived", http.StatusBadRequest},
+		{fixture.dut.transition, "200", "/subscribers/200/status?status=active", http.StatusNotFound},
+		{fixture.dut.retrieveStatus, "200", "/subscribers/200/status", http.StatusNotFound},
+		{fixture.dut.transition, "2", "/subscribers/2/status?status=bounced", http.StatusOK},
+		{fixture.dut.activate, "2", "/subscribers/2?activation_flag=true", http.StatusConflict},
+	} {
+		response = sendBody(testCase.handler, "PUT", testCase.target, testCase.index, "", "")
+		if status := response.Code; status != testCase.expectedStatus {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v", testCase.target, status,
+				testCase.expectedStatus)
+		}
+	}
+	fixture.tearDown()
+}
@@ -0,0 +1,473 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type MemoryRecords struct {
+	mutex sync.RWMutex
+	table *memoryTable
+}
+
+// memoryTable holds the subscribers without any locking. MemoryRecords guards it with a mutex,
+// and a transaction works on a copy of it that replaces the original on commit.
+type memoryTable struct {
+	subscribers map[uint64]Subscriber
+	lifecycles  map[uint64]SubscriberLifecycle
+	versions    map[uint64]uint64
+	lastIndex   uint64
+}
+
+// duplicateEntryError reads like the MySQL error for the same violation.
+type duplicateEntryError struct {
+	emailAddress string
+}
+
+func (fault duplicateEntryError) Error() string {
+	return "Duplicate entry '" + fault.emailAddress + "' for key 'subscribers.email_address'"
+}
+
+func MakeMemoryRecords() *MemoryRecords {
+	return &MemoryRecords{table: &memoryTable{subscribers: make(map[uint64]Subscriber),
+		lifecycles: make(map[uint64]SubscriberLifecycle), versions: make(map[uint64]uint64)}}
+}
+
+func (records *MemoryRecords) Create(ctx context.Context, subscriber Subscriber) (sql.Result, error) {
+	records.mutex.Lock()
+	defer records.mutex.Unlock()
+	return records.table.Create(ctx, subscriber)
+}
+
+func (records *MemoryRecords) CreateBatch(ctx context.Context, subscribers []Subscriber) (sql.Result, error) {
+	records.mutex.Lock()
+	defer records.mutex.Unlock()
+	return records.table.CreateBatch(ctx, subscribers)
+}
+
+func (records *MemoryRecords) Retrieve(ctx context.Context, index uint64) (*Subscriber, error) {
+	records.mutex.RLock()
+	defer records.mutex.RUnlock()
+	return records.table.Retrieve(ctx, index)
+}
+
+func (records *MemoryRecords) Resolve(ctx context.Context, publicID string) (uint64, error) {
+	records.mutex.RLock()
+	defer records.mutex.RUnlock()
+	return records.table.Resolve(ctx, publicID)
+}
+
+func (records *MemoryRecords) Update(ctx context.Context, update SubscriberUpdate) (sql.Result, error) {
+	records.mutex.Lock()
+	defer records.mutex.Unlock()
+	return records.table.Update(ctx, update)
+}
+
+func (records *MemoryRecords) Activate(ctx context.Context, index uint64, activate bool) (sql.Result, error) {
+	records.mutex.Lock()
+	defer records.mutex.Unlock()
+	return records.table.Activate(ctx, index, activate)
+}
+
+func (records *MemoryRecords) Transition(ctx context.Context, index uint64, status string) (sql.Result, error) {
+	records.mutex.Lock()
+	defer records.mutex.Unlock()
+	return records.table.Transition(ctx, index, status)
+}
+
+func (records *MemoryRecords) Lifecycle(ctx context.Context, index uint64) (*SubscriberLifecycle, error) {
+	records.mutex.RLock()
+	defer records.mutex.RUnlock()
+	return records.table.Lifecycle(ctx, index)
+}
+
+func (records *MemoryRecords) Version(ctx context.Context, index uint64) (uint64, error) {
+	records.mutex.RLock()
+	defer records.mutex.RUnlock()
+	return records.table.Version(ctx, index)
+}
+
+func (records *MemoryRecords) Delete(ctx context.Context, index uint64) (sql.Result, error) {
+	records.mutex.Lock()
+	defer records.mutex.Unlock()
+	return records.table.Delete(ctx, index)
+}
+
+func (records *MemoryRecords) Restore(ctx context.Context, index uint64) (sql.Result, error) {
+	records.mutex.Lock()
+	defer records.mutex.Unlock()
+	return records.table.Restore(ctx, index)
+}
+
+func (records *MemoryRecords) Purge(ctx context.Context, deletedBefore time.Time) (sql.Result, error) {
+	records.mutex.Lock()
+	defer records.mutex.Unlock()
+	return records.table.Purge(ctx, deletedBefore)
+}
+
+func (records *MemoryRecords) List(ctx context.Context, query ListQuery) ([]Subscriber, error) {
+	records.mutex.RLock()
+	defer records.mutex.RUnlock()
+	return records.table.List(ctx, query)
+}
+
+func (records *MemoryRecords) Count(ctx context.Context, query ListQuery) (int, error) {
+	records.mutex.RLock()
+	defer records.mutex.RUnlock()
+	return records.table.Count(ctx, query)
+}
+
+func (records *MemoryRecords) Transaction(ctx context.Context, work func(store SubscriberStore) error) error {
+	if contextError := ctx.Err(); contextError != nil {
+		return contextError
+	}
+	records.mutex.Lock()
+	defer records.mutex.Unlock()
+	transaction := records.table.copy()
+	if workFail := work(transaction); workFail != nil {
+		return workFail
+	}
+	if contextError := ctx.Err(); contextError != nil {
+		return contextError
+	}
+	records.table = transaction
+	return nil
+}
+
+func (table *memoryTable) copy() *memoryTable {
+	subscribers := make(map[uint64]Subscriber, len(table.subscribers))
+	for index, subscriber := range table.subscribers {
+		subscribers[index] = subscriber
+	}
+	lifecycles := make(map[uint64]SubscriberLifecycle, len(table.lifecycles))
+	for index, lifecycle := range table.lifecycles {
+		lifecycle.Transitions = append([]SubscriberTransition(nil), lifecycle.Transitions...)
+		lifecycles[index] = lifecycle
+	}
+	versions := make(map[uint64]uint64, len(table.versions))
+	for index, version := range table.versions {
+		versions[index] = version
+	}
+	return &memoryTable{subscribers, lifecycles, versions, table.lastIndex}
+}
+
+func (table *memoryTable) Create(ctx context.Context, subscriber Subscriber) (sql.Result, error) {
+	if contextError := ctx.Err(); contextError != nil {
+		return nil, contextError
+	}
+	if duplicateError := table.checkUniqueEmailAddress(0, subscriber.EmailAddress); duplicateError != nil {
+		return nil, duplicateError
+	}
+	if maxIndex == table.lastIndex {
+		return nil, errors.New("Subscriber index is exhausted.")
+	}
+	table.lastIndex++
+	now := storeTime()
+	subscriber.Index = table.lastIndex
+	subscriber.PublicID = newPublicID()
+	subscriber.ActivationFlag = false
+	subscriber.CreatedAt, subscriber.UpdatedAt = &now, &now
+	table.subscribers[subscriber.Index] = subscriber
+	table.lifecycles[subscriber.Index] = SubscriberLifecycle{Index: subscriber.Index, Status: SubscriberPending}
+	table.versions[subscriber.Index] = 1
+	return storeResult{int64(subscriber.Index), 1}, nil
+}
+
+func (table *memoryTable) CreateBatch(ctx context.Context, subscribers []Subscriber) (sql.Result, error) {
+	batch := table.copy()
+	var result sql.Result = storeResult{}
+	for _, subscriber := range subscribers {
+		var createFail error
+		if result, createFail = batch.Create(ctx, subscriber); createFail != nil {
+			return nil, createFail
+		}
+	}
+	*table = *batch
+	lastInsertId, _ := result.LastInsertId()
+	return storeResult{lastInsertId, int64(len(subscribers))}, nil
+}
+
+func (table *memoryTable) Retrieve(ctx context.Context, index uint64) (*Subscriber, error) {
+	if contextError := ctx.Err(); contextError != nil {
+		return &Subscriber{}, contextError
+	}
+	subscriber, found := table.live(index)
+	if !found {
+		return &Subscriber{}, translateFault(sql.ErrNoRows)
+	}
+	return &subscriber, nil
+}
+
+func (table *memoryTable) Resolve(ctx context.Context, publicID string) (uint64, error) {
+	if contextError := ctx.Err(); contextError != nil {
+		return 0, contextError
+	}
+	for index, subscriber := range table.subscribers {
+		if publicID == subscriber.PublicID {
+			return index, nil
+		}
+	}
+	return 0, translateFault(sql.ErrNoRows)
+}
+
+func (table *memoryTable) Update(ctx context.Context, update SubscriberUpdate) (sql.Result, error) {
+	if contextError := ctx.Err(); contextError != nil {
+		return nil, contextError
+	}
+	columnValues, validationError := update.columnValues()
+	if validationError != nil {
+		return nil, validationError
+	}
+	record, found := table.live(update.Index)
+	if !found {
+		return storeResult{}, nil
+	}
+	for _, columnValue := range columnValues {
+		switch columnValue.column {
+		case "email_address":
+			if duplicateError := table.checkUniqueEmailAddress(update.Index, columnValue.value); duplicateError != nil {
+				return nil, duplicateError
+			}
+			record.EmailAddress = columnValue.value
+		case "last_name":
+			record.LastName = columnValue.value
+		case "first_name":
+			record.FirstName = columnValue.value
+		}
+	}
+	now := storeTime()
+	record.UpdatedAt = &now
+	table.subscribers[update.Index] = record
+	table.versions[update.Index]++
+	return storeResult{0, 1}, nil
+}
+
+func (table *memoryTable) Activate(ctx context.Context, index uint64, activate bool) (sql.Result, error) {
+	return table.Transition(ctx, index, activationStatus(activate))
+}
+
+func (table *memoryTable) Transition(ctx context.Context, index uint64, status string) (sql.Result, error) {
+	if contextError := ctx.Err(); contextError != nil {
+		return nil, contextError
+	}
+	lifecycle, found := table.lifecycles[index]
+	if _, live := table.live(index); !found || !live {
+		return storeResult{}, nil
+	}
+	if transitionError := checkTransition(lifecycle.Status, status); transitionError != nil {
+		return nil, transitionError
+	}
+	if lifecycle.Status == status {
+		return storeResult{0, 1}, nil
+	}
+	now := storeTime()
+	lifecycle.Transitions = append(lifecycle.Transitions, SubscriberTransition{lifecycle.Status, status, now})
+	lifecycle.Status = status
+	table.lifecycles[index] = lifecycle
+	record := table.subscribers[index]
+	record.ActivationFlag = SubscriberActive == status
+	record.UpdatedAt = &now
+	table.subscribers[index] = record
+	table.versions[index]++
+	return storeResult{0, 1}, nil
+}
+
+func (table *memoryTable) Lifecycle(ctx context.Context, index uint64) (*SubscriberLifecycle, error) {
+	if contextError := ctx.Err(); contextError != nil {
+		return &SubscriberLifecycle{}, contextError
+	}
+	lifecycle, found := table.lifecycles[index]
+	if _, live := table.live(index); !found || !live {
+		return &SubscriberLifecycle{}, translateFault(sql.ErrNoRows)
+	}
+	lifecycle.Transitions = append(make([]SubscriberTransition, 0, len(lifecycle.Transitions)), lifecycle.Transitions...)
+	return &lifecycle, nil
+}
+
+func (table *memoryTable) Version(ctx context.Context, index uint64) (uint64, error) {
+	if contextError := ctx.Err(); contextError != nil {
+		return 0, contextError
+	}
+	version, found := table.versions[index]
+	if _, live := table.live(index); !found || !live {
+		return 0, translateFault(sql.ErrNoRows)
+	}
+	return version, nil
+}
+
+func (table *memoryTable) Delete(ctx context.Context, index uint64) (sql.Result, error) {
+	if contextError := ctx.Err(); contextError != nil {
+		return nil, contextError
+	}
+	record, found := table.live(index)
+	if !found {
+		return storeResult{}, nil
+	}
+	now := storeTime()
+	record.DeletedAt, record.UpdatedAt = &now, &now
+	table.subscribers[index] = record
+	table.versions[index]++
+	return storeResult{0, 1}, nil
+}
+
+func (table *memoryTable) Restore(ctx context.Context, index uint64) (sql.Result, error) {
+	if contextError := ctx.Err(); contextError != nil {
+		return nil, contextError
+	}
+	record, found := table.subscribers[index]
+	if !found || nil == record.DeletedAt {
+		return storeResult{}, nil
+	}
+	now := storeTime()
+	record.DeletedAt, record.UpdatedAt = nil, &now
+	table.subscribers[index] = record
+	table.versions[index]++
+	return storeResult{0, 1}, nil
+}
+
+func (table *memoryTable) Purge(ctx context.Context, deletedBefore time.Time) (sql.Result, error) {
+	if contextError := ctx.Err(); contextError != nil {
+		return nil, contextError
+	}
+	var purged int64
+	for index, subscriber := range table.subscribers {
+		if subscriber.DeletedAt != nil && subscriber.DeletedAt.Before(deletedBefore) {
+			delete(table.subscribers, index)
+			delete(table.lifecycles, index)
+			delete(table.versions, index)
+			purged++
+		}
+	}
+	return storeResult{0, purged}, nil
+}
+
+func (table *memoryTable) List(ctx context.Context, query ListQuery) ([]Subscriber, error) {
+	if contextError := ctx.Err(); contextError != nil {
+		return nil, contextError
+	}
+	if validationError := query.validate(); validationError != nil {
+		return nil, validationError
+	}
+	subscribers := make([]Subscriber, 0, len(table.subscribers))
+	for _, subscriber := range table.subscribers {
+		if query.matches(subscriber) &&
+			(0 == query.After || subscriber.Index > query.After) && (0 == query.Before || subscriber.Index < query.Before) {
+			subscribers = append(subscribers, subscriber)
+		}
+	}
+	sort.Slice(subscribers, func(left, right int) bool {
+		return query.less(subscribers[left], subscribers[right])
+	})
+	if 0 == query.Limit {
+		return subscribers, nil
+	}
+	if 0 != query.Before {
+		if len(subscribers) > query.Limit {
+			subscribers = subscribers[len(subscribers)-query.Limit:]
+		}
+		return subscribers, nil
+	}
+	if query.Offset >= len(subscribers) {
+		return subscribers[:0], nil
+	}
+	subscribers = subscribers[query.Offset:]
+	if len(subscribers) > query.Limit {
+		subscribers = subscribers[:query.Limit]
+	}
+	return subscribers, nil
+}
+
+func (table *memoryTable) Count(ctx context.Context, query ListQuery) (int, error) {
+	if contextError := ctx.Err(); contextError != nil {
+		return 0, contextError
+	}
+	count := 0
+	for _, subscriber := range table.subscribers {
+		if query.matches(subscriber) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (table *memoryTable) Transaction(ctx context.Context, work func(store SubscriberStore) error) error {
+	if contextError := ctx.Err(); contextError != nil {
+		return contextError
+	}
+	return work(table)
+}
+
+// live finds a subscriber that is not deleted.
+func (table *memoryTable) live(index uint64) (Subscriber, bool) {
+	subscriber, found := table.subscribers[index]
+	return subscriber, found && nil == subscriber.DeletedAt
+}
+
+func (table *memoryTable) checkUniqueEmailAddress(index uint64, emailAddress string) error {
+	for _, subscriber := range table.subscribers {
+		if subscriber.Index != index && subscriber.EmailAddress == emailAddress {
+			return translateFault(duplicateEntryError{emailAddress})
+		}
+	}
+	return nil
+}
+
+// matches and less mirror the where conditions and the order by clause of Records.List.
+func (query ListQuery) matches(subscriber Subscriber) bool {
+	if query.Deleted != (subscriber.DeletedAt != nil) {
+		return false
+	}
+	if query.ActivationFlag != nil && *query.ActivationFlag != subscriber.ActivationFlag {
+		return false
+	}
+	if "" != query.EmailAddress && query.EmailAddress != subscriber.EmailAddress {
+		return false
+	}
+	if "" != query.EmailDomain &&
+		!strings.HasSuffix(strings.ToLower(subscriber.EmailAddress), "@"+strings.ToLower(query.EmailDomain)) {
+		return false
+	}
+	if !query.UpdatedSince.IsZero() && (nil == subscriber.UpdatedAt || subscriber.UpdatedAt.Before(query.UpdatedSince)) {
+		return false
+	}
+	prefix := strings.ToLower(query.NamePrefix)
+	return strings.HasPrefix(strings.ToLower(subscriber.FirstName), prefix) ||
+		strings.HasPrefix(strings.ToLower(subscriber.LastName), prefix)
+}
+
+func (query ListQuery) less(left Subscriber, right Subscriber) bool {
+	leftValue, rightValue := sortValue(left, query.Sort), sortValue(right, query.Sort)
+	if leftValue == rightValue {
+		if query.Descending {
+			return left.Index > right.Index
+		}
+		return left.Index < right.Index
+	}
+	if query.Descending {
+		return leftValue > rightValue
+	}
+	return leftValue < rightValue
+}
+
+func sortValue(subscriber Subscriber, field string) string {
+	switch field {
+	case "email_address":
+		return strings.ToLower(subscriber.EmailAddress)
+	case "first_name":
+		return strings.ToLower(subscriber.FirstName)
+	case "last_name":
+		return strings.ToLower(subscriber.LastName)
+	}
+	return ""
+}
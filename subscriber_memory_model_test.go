@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestMemoryModelRejectsDuplicateEmailAddress(t *testing.T) {
+	dut := MakeMemoryRecords()
+	_, createFail := dut.Create(context.Background(), Subscriber{EmailAddress: "riseofskywalker@starwars.com"})
+	if createFail != nil {
+		t.Fatalf("ERROR creating record. %s", createFail.Error())
+	}
+	if _, duplicateFail := dut.Create(context.Background(), Subscriber{EmailAddress: "riseofskywalker@starwars.com"}); duplicateFail == nil {
+		t.Errorf("ERROR duplicate email address was accepted on create.")
+	}
+	_, createFail = dut.Create(context.Background(), Subscriber{EmailAddress: "kevin.andrews@email.com"})
+	if createFail != nil {
+		t.Fatalf("ERROR creating record. %s", createFail.Error())
+	}
+	emailAddress := "riseofskywalker@starwars.com"
+	if _, duplicateFail := dut.Update(context.Background(), SubscriberUpdate{Index: 2, EmailAddress: &emailAddress}); duplicateFail == nil {
+		t.Errorf("ERROR duplicate email address was accepted on update.")
+	}
+}
+
+func TestMemoryModelMissingRecord(t *testing.T) {
+	dut := MakeMemoryRecords()
+	if _, retrieveFail := dut.Retrieve(context.Background(), 1); !errors.Is(retrieveFail, sql.ErrNoRows) {
+		t.Errorf("ERROR expected %v when retrieving a missing record, got %v", sql.ErrNoRows, retrieveFail)
+	}
+	result, deleteFail := dut.Delete(context.Background(), 1)
+	if deleteFail != nil {
+		t.Fatalf("ERROR deleting a missing record. %s", deleteFail.Error())
+	}
+	if rowsAffected, _ := result.RowsAffected(); 0 != rowsAffected {
+		t.Errorf("ERROR deleting a missing record affected %d rows.", rowsAffected)
+	}
+}
+
+func TestMemoryModelConcurrentCreate(t *testing.T) {
+	dut := MakeMemoryRecords()
+	var waitGroup sync.WaitGroup
+	for count := 0; count < 100; count++ {
+		waitGroup.Add(1)
+		go func(count int) {
+			defer waitGroup.Done()
+			if _, createFail := dut.Create(context.Background(), Subscriber{EmailAddress: strconv.Itoa(count) + "@email.com"}); createFail != nil {
+				t.Errorf("ERROR creating record. %s", createFail.Error())
+			}
+		}(count)
+	}
+	waitGroup.Wait()
+	subscribers, listFail := dut.List(context.Background(), ListQuery{})
+	if listFail != nil {
+		t.Fatalf("ERROR fetching records. %s", listFail.Error())
+	}
+	for position, subscriber := range subscribers {
+		if uint64(position+1) != subscriber.Index {
+			t.Errorf("ERROR expected index %d, got %d", position+1, subscriber.Index)
+		}
+	}
+	if 100 != len(subscribers) {
+		t.Errorf("ERROR expected 100 records, got %d", len(subscribers))
+	}
+}
@@ -7,98 +7,568 @@
 package MarcGoRESTAPIDemo
 
 import (
+	"context"
 	"database/sql"
-	_ "github.com/go-sql-driver/mysql"
-	"strconv"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 )
 
-var settings = readConfiguration("resources/MarcGoRESTAPIDemo.yaml")
+type SubscriberStore interface {
+	Create(ctx context.Context, subscriber Subscriber) (sql.Result, error)
+	// CreateBatch creates all subscribers in one statement, or none of them if any is rejected.
+	CreateBatch(ctx context.Context, subscribers []Subscriber) (sql.Result, error)
+	Retrieve(ctx context.Context, index uint64) (*Subscriber, error)
+	Resolve(ctx context.Context, publicID string) (uint64, error)
+	Update(ctx context.Context, update SubscriberUpdate) (sql.Result, error)
+	// Activate moves the subscriber to the active or the deactivated status.
+	Activate(ctx context.Context, index uint64, activate bool) (sql.Result, error)
+	Transition(ctx context.Context, index uint64, status string) (sql.Result, error)
+	Lifecycle(ctx context.Context, index uint64) (*SubscriberLifecycle, error)
+	// Version tells the version of a subscriber, which every change to the subscriber increments.
+	// Within a transaction, the subscriber stays locked until the transaction ends.
+	Version(ctx context.Context, index uint64) (uint64, error)
+	// Delete moves the subscriber to the trash. Deleted subscribers are left out of everything but a list of deleted
+	// subscribers, until they are restored or purged.
+	Delete(ctx context.Context, index uint64) (sql.Result, error)
+	Restore(ctx context.Context, index uint64) (sql.Result, error)
+	// Purge permanently removes the subscribers that were deleted before deletedBefore.
+	Purge(ctx context.Context, deletedBefore time.Time) (sql.Result, error)
+	List(ctx context.Context, query ListQuery) ([]Subscriber, error)
+	Count(ctx context.Context, query ListQuery) (int, error)
+	// Transaction runs work with a store scoped to one transaction. Work must only use that store.
+	// The transaction commits when work returns nil and rolls back when it returns an error or panics.
+	Transaction(ctx context.Context, work func(store SubscriberStore) error) error
+}
+
+type executor interface {
+	ExecContext(ctx context.Context, query string, arguments ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, arguments ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, arguments ...interface{}) *sql.Row
+}
 
 type Records struct {
-	database *sql.DB
+	database     *sql.DB
+	transaction  *sql.Tx
+	dialect      dialect
+	queryTimeout time.Duration
 }
 
 type Subscriber struct {
-	Index          uint8  `json:"index,omitempty"`
-	EmailAddress   string `json:"email_address,omitempty"`
-	FirstName      string `json:"first_name,omitempty"`
-	LastName       string `json:"last_name,omitempty"`
-	ActivationFlag bool   `json:"activation_flag,omitempty"`
+	Index uint64 `json:"index,omitempty" xml:"index,omitempty" yaml:"index,omitempty"`
+	// The store gives every subscriber a PublicID, a UUID that can stand in for the index in URLs.
+	PublicID       string `json:"id,omitempty" xml:"id,omitempty" yaml:"id,omitempty"`
+	EmailAddress   string `json:"email_address,omitempty" xml:"email_address" yaml:"email_address"`
+	FirstName      string `json:"first_name,omitempty" xml:"first_name" yaml:"first_name"`
+	LastName       string `json:"last_name,omitempty" xml:"last_name" yaml:"last_name"`
+	ActivationFlag bool   `json:"activation_flag,omitempty" xml:"activation_flag" yaml:"activation_flag"`
+	// The store maintains CreatedAt and UpdatedAt. They are nil on subscribers that were not read from a store.
+	CreatedAt *time.Time `json:"created_at,omitempty" xml:"created_at,omitempty" yaml:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" xml:"updated_at,omitempty" yaml:"updated_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" xml:"deleted_at,omitempty" yaml:"deleted_at,omitempty"`
+}
+
+// A nil field is left unchanged and a field pointing to an empty string is cleared.
+type SubscriberUpdate struct {
+	Index        uint64
+	EmailAddress *string
+	LastName     *string
+	FirstName    *string
+}
+
+type columnValue struct {
+	column string
+	value  string
+}
+
+var subscriberUpdateFields = []string{"email_address", "last_name", "first_name"}
+
+func MakeSubscriberUpdate(index uint64, fields map[string]string) (SubscriberUpdate, error) {
+	update := SubscriberUpdate{Index: index}
+	for field, value := range fields {
+		value := value
+		switch field {
+		case "email_address":
+			update.EmailAddress = &value
+		case "last_name":
+			update.LastName = &value
+		case "first_name":
+			update.FirstName = &value
+		default:
+			return update, ValidationError{field, "Subscriber field " + field + " cannot be updated. Updatable fields are " +
+				strings.Join(subscriberUpdateFields, ", ") + "."}
+		}
+	}
+	_, validationError := update.columnValues()
+	return update, validationError
+}
+
+func (update SubscriberUpdate) columnValues() ([]columnValue, error) {
+	var columnValues []columnValue
+	for _, field := range []struct {
+		column string
+		value  *string
+	}{
+		{"email_address", update.EmailAddress},
+		{"last_name", update.LastName},
+		{"first_name", update.FirstName},
+	} {
+		if field.value != nil {
+			columnValues = append(columnValues, columnValue{field.column, *field.value})
+		}
+	}
+	if 0 == len(columnValues) {
+		return nil, ValidationError{"", "No subscriber fields to update."}
+	}
+	if update.EmailAddress != nil && "" == *update.EmailAddress {
+		return nil, ValidationError{"email_address", "Subscriber email_address cannot be cleared."}
+	}
+	return columnValues, nil
+}
+
+func (update SubscriberUpdate) subscriber() Subscriber {
+	subscriber := Subscriber{Index: update.Index}
+	if update.EmailAddress != nil {
+		subscriber.EmailAddress = *update.EmailAddress
+	}
+	if update.LastName != nil {
+		subscriber.LastName = *update.LastName
+	}
+	if update.FirstName != nil {
+		subscriber.FirstName = *update.FirstName
+	}
+	return subscriber
+}
+
+// ListQuery selects a page of subscribers. A zero Limit lists every subscriber.
+// After and Before are exclusive keyset bounds on the index and need the default ascending index order.
+// A non-zero UpdatedSince keeps the subscribers updated at or after that time. Deleted lists the deleted subscribers
+// instead of the others.
+// Count honours the filters but ignores Limit, Offset, After and Before.
+type ListQuery struct {
+	Limit          int
+	Offset         int
+	After          uint64
+	Before         uint64
+	ActivationFlag *bool
+	EmailAddress   string
+	EmailDomain    string
+	NamePrefix     string
+	UpdatedSince   time.Time
+	Deleted        bool
+	Sort           string
+	Descending     bool
+}
+
+var listSortFields = []string{"index", "email_address", "first_name", "last_name"}
+
+func (query ListQuery) validate() error {
+	if query.Limit < 0 || query.Offset < 0 {
+		return ValidationError{"", "Limit and offset cannot be negative."}
+	}
+	if 0 != query.Offset && 0 == query.Limit {
+		return ValidationError{"offset", "An offset needs a limit."}
+	}
+	if 0 != query.Offset && (0 != query.After || 0 != query.Before) {
+		return ValidationError{"offset", "An offset cannot be combined with a cursor."}
+	}
+	if 0 != query.After && 0 != query.Before {
+		return ValidationError{"cursor", "A cursor cannot be both after and before an index."}
+	}
+	if (0 != query.After || 0 != query.Before) && !query.orderedByIndex() {
+		return ValidationError{"cursor", "A cursor can only be used when sorting by ascending index."}
+	}
+	if "" != query.Sort && !sortableField(query.Sort) {
+		return ValidationError{"sort", "Subscribers cannot be sorted by " + query.Sort + "."}
+	}
+	return nil
+}
+
+func (query ListQuery) orderedByIndex() bool {
+	return ("" == query.Sort || "index" == query.Sort) && !query.Descending
+}
+
+func sortableField(field string) bool {
+	for _, sortField := range listSortFields {
+		if sortField == field {
+			return true
+		}
+	}
+	return false
+}
+
+// conditions translates the filters into where conditions. Patterns are matched case-insensitively,
+// with the like wildcards of the filter values escaped.
+func (query ListQuery) conditions() ([]string, []interface{}) {
+	conditions := []string{"`deleted_at` is null"}
+	if query.Deleted {
+		conditions[0] = "`deleted_at` is not null"
+	}
+	var arguments []interface{}
+	if query.ActivationFlag != nil {
+		activationFlag := 0
+		if *query.ActivationFlag {
+			activationFlag = 1
+		}
+		conditions = append(conditions, "`activation_flag` = ?")
+		arguments = append(arguments, activationFlag)
+	}
+	if "" != query.EmailAddress {
+		conditions = append(conditions, "`email_address` = ?")
+		arguments = append(arguments, query.EmailAddress)
+	}
+	if "" != query.EmailDomain {
+		conditions = append(conditions, "lower(`email_address`) like ? escape '!'")
+		arguments = append(arguments, "%@"+escapeLike(strings.ToLower(query.EmailDomain)))
+	}
+	if "" != query.NamePrefix {
+		prefix := escapeLike(strings.ToLower(query.NamePrefix)) + "%"
+		conditions = append(conditions, "(lower(`first_name`) like ? escape '!' or lower(`last_name`) like ? escape '!')")
+		arguments = append(arguments, prefix, prefix)
+	}
+	if !query.UpdatedSince.IsZero() {
+		conditions = append(conditions, "`updated_at` >= ?")
+		arguments = append(arguments, query.UpdatedSince.UTC())
+	}
+	return conditions, arguments
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
+
+const subscriberColumns = "`index`, `public_id`, `email_address`, `last_name`, `first_name`, `activation_flag`, `created_at`, " +
+	"`updated_at`, `deleted_at`"
+
+// storeTime is the time a store records a change at, in UTC and to the microsecond that every database keeps.
+func storeTime() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+type scanner interface {
+	Scan(destinations ...interface{}) error
+}
+
+type storeResult struct {
+	lastInsertId int64
+	rowsAffected int64
+}
+
+func (result storeResult) LastInsertId() (int64, error) {
+	return result.lastInsertId, nil
+}
+
+func (result storeResult) RowsAffected() (int64, error) {
+	return result.rowsAffected, nil
 }
 
 func MakeDatabaseRecords() Records {
-	database, dbInstanceFail := sql.Open("mysql", settings.Database.User+":"+settings.Database.Password+
-		"@tcp("+settings.Database.Host+":"+strconv.Itoa(int(settings.Database.Port))+")/"+settings.Database.DBName)
+	return makeDatabaseRecords(settings().Database)
+}
+
+func makeDatabaseRecords(configuration DatabaseConfiguration) Records {
+	records, fault := OpenDatabaseRecords(configuration)
+	if fault != nil {
+		panic(fault.Error())
+	}
+	return records
+}
+
+// OpenDatabaseRecords opens the configured database, and migrates it up with AutoMigrate.
+func OpenDatabaseRecords(configuration DatabaseConfiguration) (Records, error) {
+	dialect, supported := dialects[configuration.Driver]
+	if !supported {
+		return Records{}, errors.New("Database driver " + configuration.Driver + " is not supported.")
+	}
+	database, dbInstanceFail := sql.Open(dialect.driver(), dialect.dataSourceName(configuration))
 	if dbInstanceFail != nil {
-		panic(dbInstanceFail.Error())
+		return Records{}, dbInstanceFail
+	}
+	if _, isSQLite := dialect.(sqliteDialect); isSQLite {
+		database.SetMaxOpenConns(1)
 	}
-	return Records{database}
+	records := Records{database: database, dialect: dialect, queryTimeout: configuration.QueryTimeout}
+	if configuration.AutoMigrate {
+		if migrateFail := records.MigrateUp(); migrateFail != nil {
+			database.Close()
+			return Records{}, migrateFail
+		}
+	}
+	return records, nil
 }
 
-func (records Records) create(subscriber Subscriber) (sql.Result, error) {
-	result, fault := records.database.Exec(
-		"insert into `subscribers` (`email_address`, `last_name`, `first_name`) values (?, ?, ?)",
-		subscriber.EmailAddress, subscriber.LastName, subscriber.FirstName)
-	return result, fault
+// Close closes the database. A store scoped to a transaction leaves the database open.
+func (records Records) Close() error {
+	if records.transaction != nil {
+		return nil
+	}
+	return records.database.Close()
 }
 
-func (records Records) retrieve(index uint8) (*Subscriber, error) {
-	var subscriber Subscriber
-	record := records.database.QueryRow("select * from `subscribers` where `index`=?", index)
-	recordModelError := record.Scan(&subscriber.Index, &subscriber.EmailAddress, &subscriber.LastName, &subscriber.FirstName,
-		&subscriber.ActivationFlag)
-	return &subscriber, recordModelError
+// Ping tells whether the database can be reached.
+func (records Records) Ping(ctx context.Context) error {
+	ctx, cancel := records.withTimeout(ctx)
+	defer cancel()
+	return storeFault(ctx, records.database.PingContext(ctx))
 }
 
-func (records Records) update(subscriber Subscriber) (sql.Result, error) {
-	var parametersToUpdate []string
-	if "" != subscriber.EmailAddress {
-		parametersToUpdate = append(parametersToUpdate, "`email_address` = "+"\""+subscriber.EmailAddress+"\"")
+func (records Records) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if 0 == records.queryTimeout {
+		return context.WithCancel(ctx)
 	}
-	if "" != subscriber.LastName {
-		parametersToUpdate = append(parametersToUpdate, "`last_name` = "+"\""+subscriber.LastName+"\"")
+	return context.WithTimeout(ctx, records.queryTimeout)
+}
+
+// Drivers report an expired or canceled context in their own words, so the context error is wrapped around theirs.
+// Any other database error is translated into a store error.
+func storeFault(ctx context.Context, fault error) error {
+	if fault != nil && ctx.Err() != nil && !errors.Is(fault, ctx.Err()) {
+		return fmt.Errorf("%w: %v", ctx.Err(), fault)
 	}
-	if "" != subscriber.FirstName {
-		parametersToUpdate = append(parametersToUpdate, "`first_name` = "+"\""+subscriber.FirstName+"\"")
+	return translateFault(fault)
+}
+
+func (records Records) executor() executor {
+	if records.transaction != nil {
+		return records.transaction
 	}
-	result, updateFail := records.database.Exec("update `subscribers` set "+
-		strings.Join(parametersToUpdate, ",")+" where `index`=?", subscriber.Index)
-	return result, updateFail
+	return records.database
+}
+
+func (records Records) exec(ctx context.Context, statement string, arguments ...interface{}) (sql.Result, error) {
+	result, fault := records.executor().ExecContext(ctx, render(records.dialect, statement), arguments...)
+	return result, storeFault(ctx, fault)
+}
+
+func (records Records) query(ctx context.Context, statement string, arguments ...interface{}) (*sql.Rows, error) {
+	rows, fault := records.executor().QueryContext(ctx, render(records.dialect, statement), arguments...)
+	return rows, storeFault(ctx, fault)
+}
+
+func (records Records) queryRow(ctx context.Context, statement string, arguments ...interface{}) *sql.Row {
+	return records.executor().QueryRowContext(ctx, render(records.dialect, statement), arguments...)
+}
+
+func (records Records) truncate() error {
+	for _, statement := range records.dialect.truncate("subscribers") {
+		if _, truncateFail := records.exec(context.Background(), statement); truncateFail != nil {
+			return truncateFail
+		}
+	}
+	return nil
+}
+
+func (records Records) Create(ctx context.Context, subscriber Subscriber) (sql.Result, error) {
+	ctx, cancel := records.withTimeout(ctx)
+	defer cancel()
+	statement := "insert into `subscribers` " +
+		"(`public_id`, `email_address`, `last_name`, `first_name`, `created_at`, `updated_at`) values (?, ?, ?, ?, ?, ?)"
+	now := storeTime()
+	values := []interface{}{newPublicID(), subscriber.EmailAddress, subscriber.LastName, subscriber.FirstName, now, now}
+	if returning := records.dialect.returning("index"); "" != returning {
+		var index int64
+		if insertFail := records.queryRow(ctx, statement+returning, values...).Scan(&index); insertFail != nil {
+			return nil, storeFault(ctx, insertFail)
+		}
+		return storeResult{index, 1}, nil
+	}
+	result, fault := records.exec(ctx, statement, values...)
+	return result, fault
+}
+
+func (records Records) CreateBatch(ctx context.Context, subscribers []Subscriber) (sql.Result, error) {
+	if 0 == len(subscribers) {
+		return storeResult{}, nil
+	}
+	ctx, cancel := records.withTimeout(ctx)
+	defer cancel()
+	now := storeTime()
+	rows := make([]string, 0, len(subscribers))
+	values := make([]interface{}, 0, 6*len(subscribers))
+	for _, subscriber := range subscribers {
+		rows = append(rows, "(?, ?, ?, ?, ?, ?)")
+		values = append(values, newPublicID(), subscriber.EmailAddress, subscriber.LastName, subscriber.FirstName, now, now)
+	}
+	result, fault := records.exec(ctx, "insert into `subscribers` "+
+		"(`public_id`, `email_address`, `last_name`, `first_name`, `created_at`, `updated_at`) values "+
+		strings.Join(rows, ", "), values...)
+	return result, fault
+}
+
+func (records Records) Retrieve(ctx context.Context, index uint64) (*Subscriber, error) {
+	ctx, cancel := records.withTimeout(ctx)
+	defer cancel()
+	record := records.queryRow(ctx, "select "+subscriberColumns+" from `subscribers` where `index`=? and `deleted_at` is null",
+		index)
+	subscriber, recordModelError := scanSubscriber(record)
+	return subscriber, storeFault(ctx, recordModelError)
 }
 
-func (records Records) activate(index uint8, activate bool) (sql.Result, error) {
-	activationFlag := 0
-	if activate == true {
-		activationFlag = 1
+func (records Records) Update(ctx context.Context, update SubscriberUpdate) (sql.Result, error) {
+	columnValues, validationError := update.columnValues()
+	if validationError != nil {
+		return nil, validationError
 	}
-	result, updateFail := records.database.Exec(
-		"update `subscribers` set activation_flag=? where `index`=?", activationFlag, index)
+	ctx, cancel := records.withTimeout(ctx)
+	defer cancel()
+	parametersToUpdate := make([]string, 0, len(columnValues)+2)
+	values := make([]interface{}, 0, len(columnValues)+2)
+	for _, columnValue := range columnValues {
+		parametersToUpdate = append(parametersToUpdate, "`"+columnValue.column+"` = ?")
+		values = append(values, columnValue.value)
+	}
+	parametersToUpdate = append(parametersToUpdate, "`version` = `version` + 1", "`updated_at` = ?")
+	values = append(values, storeTime())
+	result, updateFail := records.exec(ctx, "update `subscribers` set "+
+		strings.Join(parametersToUpdate, ",")+" where `index`=? and `deleted_at` is null", append(values, update.Index)...)
 	return result, updateFail
 }
 
-func (records Records) delete(index uint8) (sql.Result, error) {
-	result, deleteError := records.database.Exec("delete from `subscribers` where `index`=?", index)
+func (records Records) Activate(ctx context.Context, index uint64, activate bool) (sql.Result, error) {
+	return records.Transition(ctx, index, activationStatus(activate))
+}
+
+func (records Records) Version(ctx context.Context, index uint64) (uint64, error) {
+	ctx, cancel := records.withTimeout(ctx)
+	defer cancel()
+	statement := "select `version` from `subscribers` where `index`=? and `deleted_at` is null"
+	if records.transaction != nil {
+		statement += records.dialect.lockRows()
+	}
+	var version uint64
+	versionFail := records.queryRow(ctx, statement, index).Scan(&version)
+	return version, storeFault(ctx, versionFail)
+}
+
+func (records Records) Delete(ctx context.Context, index uint64) (sql.Result, error) {
+	ctx, cancel := records.withTimeout(ctx)
+	defer cancel()
+	now := storeTime()
+	result, deleteError := records.exec(ctx, "update `subscribers` set `deleted_at`=?, `updated_at`=?, `version`=`version`+1 "+
+		"where `index`=? and `deleted_at` is null", now, now, index)
 	return result, deleteError
 }
 
-func (records Records) list() ([]Subscriber, error) {
-	rows, dbQueryError := records.database.Query("select * from `subscribers`")
+func (records Records) List(ctx context.Context, query ListQuery) ([]Subscriber, error) {
+	if validationError := query.validate(); validationError != nil {
+		return nil, validationError
+	}
+	ctx, cancel := records.withTimeout(ctx)
+	defer cancel()
+	conditions, arguments := query.conditions()
+	order := "asc"
+	if query.Descending {
+		order = "desc"
+	}
+	switch {
+	case 0 != query.After:
+		conditions = append(conditions, "`index` > ?")
+		arguments = append(arguments, query.After)
+	case 0 != query.Before:
+		conditions = append(conditions, "`index` < ?")
+		arguments = append(arguments, query.Before)
+		order = "desc"
+	}
+	statement := "select " + subscriberColumns + " from `subscribers`" + whereClause(conditions) + " order by "
+	if "" != query.Sort && "index" != query.Sort {
+		statement += "lower(`" + query.Sort + "`) " + order + ", "
+	}
+	statement += "`index` " + order
+	if 0 != query.Limit {
+		statement += " limit ?"
+		arguments = append(arguments, query.Limit)
+		if 0 != query.Offset {
+			statement += " offset ?"
+			arguments = append(arguments, query.Offset)
+		}
+	}
+	rows, dbQueryError := records.query(ctx, statement, arguments...)
 	if dbQueryError != nil {
 		return nil, dbQueryError
 	}
 	subscribers := make([]Subscriber, 0)
 	for rows.Next() {
-		var subscriber Subscriber
-		if recordModelError := rows.Scan(&subscriber.Index, &subscriber.EmailAddress, &subscriber.LastName, &subscriber.FirstName,
-			&subscriber.ActivationFlag); recordModelError != nil {
-			return subscribers, recordModelError
+		subscriber, recordModelError := scanSubscriber(rows)
+		if recordModelError != nil {
+			return subscribers, storeFault(ctx, recordModelError)
 		}
-		subscribers = append(subscribers, subscriber)
+		subscribers = append(subscribers, *subscriber)
+	}
+	if rowsError := rows.Err(); rowsError != nil {
+		return subscribers, storeFault(ctx, rowsError)
 	}
 	rowsCloseError := rows.Close()
 	if rowsCloseError != nil {
 		return subscribers, rowsCloseError
 	}
+	if 0 != query.Before {
+		reverseSubscribers(subscribers)
+	}
 	return subscribers, nil
 }
+
+func (records Records) Count(ctx context.Context, query ListQuery) (int, error) {
+	ctx, cancel := records.withTimeout(ctx)
+	defer cancel()
+	conditions, arguments := query.conditions()
+	var count int
+	countFail := records.queryRow(ctx, "select count(*) from `subscribers`"+whereClause(conditions), arguments...).Scan(&count)
+	return count, storeFault(ctx, countFail)
+}
+
+func whereClause(conditions []string) string {
+	if 0 == len(conditions) {
+		return ""
+	}
+	return " where " + strings.Join(conditions, " and ")
+}
+
+func (records Records) Transaction(ctx context.Context, work func(store SubscriberStore) error) error {
+	if records.transaction != nil {
+		return work(records)
+	}
+	transaction, beginFail := records.database.BeginTx(ctx, nil)
+	if beginFail != nil {
+		return storeFault(ctx, beginFail)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = transaction.Rollback()
+		}
+	}()
+	scoped := records
+	scoped.transaction = transaction
+	if workFail := work(scoped); workFail != nil {
+		return workFail
+	}
+	committed = true
+	return storeFault(ctx, transaction.Commit())
+}
+
+func scanSubscriber(record scanner) (*Subscriber, error) {
+	var subscriber Subscriber
+	var createdAt, updatedAt time.Time
+	var publicID sql.NullString
+	var deletedAt sql.NullTime
+	recordModelError := record.Scan(&subscriber.Index, &publicID, &subscriber.EmailAddress, &subscriber.LastName, &subscriber.FirstName,
+		&subscriber.ActivationFlag, &createdAt, &updatedAt, &deletedAt)
+	if recordModelError != nil {
+		return &subscriber, recordModelError
+	}
+	subscriber.PublicID = publicID.String
+	createdAt, updatedAt = createdAt.UTC(), updatedAt.UTC()
+	subscriber.CreatedAt, subscriber.UpdatedAt = &createdAt, &updatedAt
+	if deletedAt.Valid {
+		deletedAt.Time = deletedAt.Time.UTC()
+		subscriber.DeletedAt = &deletedAt.Time
+	}
+	return &subscriber, nil
+}
+
+func reverseSubscribers(subscribers []Subscriber) {
+	for left, right := 0, len(subscribers)-1; left < right; left, right = left+1, right-1 {
+		subscribers[left], subscribers[right] = subscribers[right], subscribers[left]
+	}
+}
@@ -7,7 +7,12 @@
 package MarcGoRESTAPIDemo
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -17,11 +22,79 @@ func init() {
 }
 
 type SubscriberModelTestFixture struct {
-	dut             Records
+	dut             SubscriberStore
 	expectedRecords []Subscriber
+	tearDown        func()
 }
 
-func setupSubscriberModelTestFixture() SubscriberModelTestFixture {
+type subscriberStoreMaker func() (SubscriberStore, func(), error)
+
+var subscriberStoreMakers = map[string]subscriberStoreMaker{
+	"memory": func() (SubscriberStore, func(), error) {
+		return MakeMemoryRecords(), func() {}, nil
+	},
+	"mysql": func() (SubscriberStore, func(), error) {
+		return makeDatabaseTestStore(settings().Database)
+	},
+	"postgres": func() (SubscriberStore, func(), error) {
+		return makeDatabaseTestStore(DatabaseConfiguration{
+			Driver:      "postgres",
+			Host:        settings().Database.Host,
+			Port:        5432,
+			DBName:      settings().Database.DBName,
+			User:        settings().Database.User,
+			Password:    settings().Database.Password,
+			SSLMode:     "disable",
+			AutoMigrate: true,
+		})
+	},
+	"sqlite3": func() (SubscriberStore, func(), error) {
+		directory, directoryFail := os.MkdirTemp("", "MarcGoRESTAPIDemo")
+		if directoryFail != nil {
+			return nil, nil, directoryFail
+		}
+		records := makeDatabaseRecords(DatabaseConfiguration{
+			Driver:      "sqlite3",
+			DBName:      filepath.Join(directory, "subscribers_database.db"),
+			AutoMigrate: true,
+		})
+		return records, func() {
+			dbCloseFail := records.database.Close()
+			if dbCloseFail != nil {
+				panic(dbCloseFail.Error())
+			}
+			if removeFail := os.RemoveAll(directory); removeFail != nil {
+				panic(removeFail.Error())
+			}
+		}, nil
+	},
+}
+
+func makeDatabaseTestStore(configuration DatabaseConfiguration) (SubscriberStore, func(), error) {
+	dialect := dialectOf(configuration.Driver)
+	database, dbInstanceFail := sql.Open(dialect.driver(), dialect.dataSourceName(configuration))
+	if dbInstanceFail != nil {
+		return nil, nil, dbInstanceFail
+	}
+	noDBConnection := database.Ping()
+	database.Close()
+	if noDBConnection != nil {
+		return nil, nil, noDBConnection
+	}
+	records := makeDatabaseRecords(configuration)
+	return records, func() {
+		truncateFail := records.truncate()
+		if truncateFail != nil {
+			panic(truncateFail.Error())
+		}
+		dbCloseFail := records.database.Close()
+		if dbCloseFail != nil {
+			panic(dbCloseFail.Error())
+		}
+	}, nil
+}
+
+func setupSubscriberModelTestFixture(makeStore subscriberStoreMaker) (SubscriberModelTestFixture, error) {
 	expectedRecords := []Subscriber{
 		{
 			Index:          1,
@@ -45,155 +118,303 @@ func setupSubscriberModelTestFixture() SubscriberModelTestFixture {
 			ActivationFlag: false,
 		},
 	}
-	dut := MakeDatabaseRecords()
-	noDBConnection := dut.database.Ping()
-	if noDBConnection != nil {
-		panic(noDBConnection.Error())
+	dut, tearDown, storeFail := makeStore()
+	if storeFail != nil {
+		return SubscriberModelTestFixture{}, storeFail
 	}
 	for _, subscriber := range expectedRecords {
-		_, createError := dut.create(subscriber)
+		_, createError := dut.Create(context.Background(), subscriber)
 		if createError != nil {
+			tearDown()
 			panic(createError.Error())
 		}
 	}
-	return SubscriberModelTestFixture{dut, expectedRecords}
+	return SubscriberModelTestFixture{dut, expectedRecords, tearDown}, nil
 }
 
-func (fixture SubscriberModelTestFixture) tearDown() {
-	fixture.expectedRecords = nil
-	_, truncateFail := fixture.dut.database.Exec("truncate table `subscribers`")
-	if truncateFail != nil {
-		panic(truncateFail.Error())
+func runSubscriberModelTest(t *testing.T, test func(t *testing.T, fixture SubscriberModelTestFixture)) {
+	for name, makeStore := range subscriberStoreMakers {
+		makeStore := makeStore
+		t.Run(name, func(t *testing.T) {
+			fixture, storeFail := setupSubscriberModelTestFixture(makeStore)
+			if storeFail != nil {
+				t.Skipf("Subscriber store %s is not available. %s", name, storeFail.Error())
+			}
+			defer fixture.tearDown()
+			test(t, fixture)
+		})
 	}
-	dbCloseFail := fixture.dut.database.Close()
-	if dbCloseFail != nil {
-		panic(dbCloseFail.Error())
+}
+
+// unstamped leaves out the public ID and the timestamps the store keeps, so a stored subscriber compares with the one
+// that was written.
+func unstamped(subscriber Subscriber) Subscriber {
+	subscriber.PublicID = ""
+	subscriber.CreatedAt, subscriber.UpdatedAt = nil, nil
+	return subscriber
+}
+
+func unstampedList(subscribers []Subscriber) []Subscriber {
+	unstampedSubscribers := make([]Subscriber, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		unstampedSubscribers = append(unstampedSubscribers, unstamped(subscriber))
 	}
+	return unstampedSubscribers
 }
 
 func TestCreateModel(t *testing.T) {
-	fixture := setupSubscriberModelTestFixture()
-	newRecord := Subscriber{
-		Index:          4,
-		EmailAddress:   "riseofskywalker@starwars.com",
-		FirstName:      "Palpatine",
-		LastName:       "Rey",
-		ActivationFlag: false,
-	}
-	_, createFail := fixture.dut.create(Subscriber{
-		EmailAddress: "riseofskywalker@starwars.com",
-		FirstName:    "Palpatine",
-		LastName:     "Rey",
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		newRecord := Subscriber{
+			Index:          4,
+			EmailAddress:   "riseofskywalker@starwars.com",
+			FirstName:      "Palpatine",
+			LastName:       "Rey",
+			ActivationFlag: false,
+		}
+		_, createFail := fixture.dut.Create(context.Background(), Subscriber{
+			EmailAddress: "riseofskywalker@starwars.com",
+			FirstName:    "Palpatine",
+			LastName:     "Rey",
+		})
+		if createFail != nil {
+			t.Errorf("ERROR creating database records. %s", createFail.Error())
+		}
+		fetchedRecords, listFail := fixture.dut.List(context.Background(), ListQuery{})
+		if listFail != nil {
+			t.Errorf("ERROR fetching database records. %s", listFail.Error())
+		}
+		updatedExpectedRecords := append(fixture.expectedRecords, newRecord)
+		for index := range updatedExpectedRecords {
+			if updatedExpectedRecords[index] != unstamped(fetchedRecords[index]) {
+				t.Errorf("ERROR fetching database records. Expected %v != Actual %v",
+					updatedExpectedRecords[index], fetchedRecords[index])
+			}
+		}
 	})
-	if createFail != nil {
-		t.Errorf("ERROR creating database records. %s", createFail.Error())
-	}
-	fetchedRecords, listFail := fixture.dut.list()
-	if listFail != nil {
-		t.Errorf("ERROR fetching database records. %s", listFail.Error())
-	}
-	updatedExpectedRecords := append(fixture.expectedRecords, newRecord)
-	for index := range updatedExpectedRecords {
-		if updatedExpectedRecords[index] != fetchedRecords[index] {
-			t.Errorf("ERROR fetching database records. Expected %v != Actual %v",
-				updatedExpectedRecords[index], fetchedRecords[index])
+}
+
+func TestSubscriberTimestamps(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		ctx := context.Background()
+		created, retrieveFail := fixture.dut.Retrieve(ctx, 1)
+		if retrieveFail != nil || nil == created.CreatedAt || nil == created.UpdatedAt ||
+			!created.CreatedAt.Equal(*created.UpdatedAt) {
+			t.Fatalf("ERROR a created subscriber has no timestamps: %+v %v", created, retrieveFail)
 		}
-	}
-	fixture.tearDown()
+		time.Sleep(2 * time.Millisecond)
+		since := storeTime()
+		firstName := "Luke"
+		if _, updateFail := fixture.dut.Update(ctx, SubscriberUpdate{Index: 1, FirstName: &firstName}); updateFail != nil {
+			t.Fatalf("ERROR updating a subscriber. %s", updateFail.Error())
+		}
+		if _, activateFail := fixture.dut.Activate(ctx, 3, true); activateFail != nil {
+			t.Fatalf("ERROR activating a subscriber. %s", activateFail.Error())
+		}
+		updated, _ := fixture.dut.Retrieve(ctx, 1)
+		if !created.CreatedAt.Equal(*updated.CreatedAt) || updated.UpdatedAt.Before(since) {
+			t.Errorf("ERROR updating did not keep the timestamps. Created %v, updated %v since %v",
+				*updated.CreatedAt, *updated.UpdatedAt, since)
+		}
+		activated, _ := fixture.dut.Retrieve(ctx, 3)
+		time.Sleep(2 * time.Millisecond)
+		_, _ = fixture.dut.Activate(ctx, 3, true)
+		if reactivated, _ := fixture.dut.Retrieve(ctx, 3); activated.UpdatedAt.Before(since) ||
+			!activated.UpdatedAt.Equal(*reactivated.UpdatedAt) {
+			t.Errorf("ERROR activating updated subscriber #3 at %v, then at %v", *activated.UpdatedAt, *reactivated.UpdatedAt)
+		}
+		unchanged, _ := fixture.dut.Retrieve(ctx, 2)
+		if !created.CreatedAt.Before(since) || unchanged.UpdatedAt.After(since) {
+			t.Errorf("ERROR an unchanged subscriber was updated at %v, after %v", *unchanged.UpdatedAt, since)
+		}
+		fetchedRecords, listFail := fixture.dut.List(ctx, ListQuery{UpdatedSince: since})
+		if listFail != nil {
+			t.Fatalf("ERROR listing updated subscribers. %s", listFail.Error())
+		}
+		if 2 != len(fetchedRecords) || 1 != fetchedRecords[0].Index || 3 != fetchedRecords[1].Index {
+			t.Errorf("ERROR listing subscribers updated since %v returned %v", since, fetchedRecords)
+		}
+		if count, countFail := fixture.dut.Count(ctx, ListQuery{UpdatedSince: since}); countFail != nil || 2 != count {
+			t.Errorf("ERROR counting subscribers updated since %v returned %d. %v", since, count, countFail)
+		}
+	})
 }
 
 func TestRetrieveModel(t *testing.T) {
-	fixture := setupSubscriberModelTestFixture()
-	fetchedRecords, listFail := fixture.dut.list()
-	if listFail != nil {
-		t.Errorf("ERROR fetching database records. %s", listFail.Error())
-	}
-	for index := range fixture.expectedRecords {
-		if fixture.expectedRecords[index] != fetchedRecords[index] {
-			t.Errorf("ERROR fetching database record. Expected %v != Actual %v",
-				fixture.expectedRecords[index], fetchedRecords[index])
-		}
-		retrievedRecord, retrieveFail := fixture.dut.retrieve(uint8(index + 1))
-		if retrieveFail != nil {
-			t.Errorf("ERROR retrieving database record at index %d. %s", index+1, retrieveFail.Error())
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		fetchedRecords, listFail := fixture.dut.List(context.Background(), ListQuery{})
+		if listFail != nil {
+			t.Errorf("ERROR fetching database records. %s", listFail.Error())
 		}
-		if fixture.expectedRecords[index] != *retrievedRecord {
-			t.Errorf("ERROR fetching database record. Expected %v != Actual %v",
-				fixture.expectedRecords[index], retrievedRecord)
+		for index := range fixture.expectedRecords {
+			if fixture.expectedRecords[index] != unstamped(fetchedRecords[index]) {
+				t.Errorf("ERROR fetching database record. Expected %v != Actual %v",
+					fixture.expectedRecords[index], fetchedRecords[index])
+			}
+			retrievedRecord, retrieveFail := fixture.dut.Retrieve(context.Background(), uint64(index+1))
+			if retrieveFail != nil {
+				t.Errorf("ERROR retrieving database record at index %d. %s", index+1, retrieveFail.Error())
+			}
+			if fixture.expectedRecords[index] != unstamped(*retrievedRecord) {
+				t.Errorf("ERROR fetching database record. Expected %v != Actual %v",
+					fixture.expectedRecords[index], retrievedRecord)
+			}
 		}
-	}
-	fixture.tearDown()
+	})
 }
 
 func TestUpdateModel(t *testing.T) {
-	fixture := setupSubscriberModelTestFixture()
-	form := Subscriber{}
-	form.Index = uint8(rand.Intn(len(fixture.expectedRecords)) + 1)
-	form.FirstName = "Handsome Marc"
-	form.EmailAddress = "marchandsome@yeahmail.com"
-	_, updateFail := fixture.dut.update(form)
-	if updateFail != nil {
-		t.Errorf("ERROR updating database records. %s", updateFail.Error())
-	}
-	fetchedRecords, listFail := fixture.dut.list()
-	if listFail != nil {
-		t.Errorf("ERROR fetching database records. %s", listFail.Error())
-	}
-	updatedExpectedRecords := fixture.expectedRecords
-	updatedExpectedRecords[form.Index-1].FirstName = form.FirstName
-	updatedExpectedRecords[form.Index-1].EmailAddress = form.EmailAddress
-	for index := range updatedExpectedRecords {
-		if updatedExpectedRecords[index] != fetchedRecords[index] {
-			t.Errorf("ERROR fetching database records. Expected %v != Actual %v",
-				updatedExpectedRecords[index], fetchedRecords[index])
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		firstName := "Handsome Marc"
+		emailAddress := "marchandsome@yeahmail.com"
+		form := SubscriberUpdate{}
+		form.Index = uint64(rand.Intn(len(fixture.expectedRecords)) + 1)
+		form.FirstName = &firstName
+		form.EmailAddress = &emailAddress
+		_, updateFail := fixture.dut.Update(context.Background(), form)
+		if updateFail != nil {
+			t.Errorf("ERROR updating database records. %s", updateFail.Error())
 		}
-	}
-	fixture.tearDown()
+		fetchedRecords, listFail := fixture.dut.List(context.Background(), ListQuery{})
+		if listFail != nil {
+			t.Errorf("ERROR fetching database records. %s", listFail.Error())
+		}
+		updatedExpectedRecords := fixture.expectedRecords
+		updatedExpectedRecords[form.Index-1].FirstName = firstName
+		updatedExpectedRecords[form.Index-1].EmailAddress = emailAddress
+		for index := range updatedExpectedRecords {
+			if updatedExpectedRecords[index] != unstamped(fetchedRecords[index]) {
+				t.Errorf("ERROR fetching database records. Expected %v != Actual %v",
+					updatedExpectedRecords[index], fetchedRecords[index])
+			}
+		}
+	})
+}
+
+func TestUpdateModelBindsAndClearsValues(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		lastName := `O"Brien', "x"); drop table subscribers; --`
+		firstName := ""
+		_, updateFail := fixture.dut.Update(context.Background(), SubscriberUpdate{Index: 1, LastName: &lastName, FirstName: &firstName})
+		if updateFail != nil {
+			t.Fatalf("ERROR updating database records. %s", updateFail.Error())
+		}
+		retrievedRecord, retrieveFail := fixture.dut.Retrieve(context.Background(), 1)
+		if retrieveFail != nil {
+			t.Fatalf("ERROR retrieving database record. %s", retrieveFail.Error())
+		}
+		expectedRecord := fixture.expectedRecords[0]
+		expectedRecord.LastName = lastName
+		expectedRecord.FirstName = firstName
+		if expectedRecord != unstamped(*retrievedRecord) {
+			t.Errorf("ERROR fetching database record. Expected %v != Actual %v", expectedRecord, *retrievedRecord)
+		}
+		emailAddress := ""
+		if _, clearFail := fixture.dut.Update(context.Background(), SubscriberUpdate{Index: 1, EmailAddress: &emailAddress}); clearFail == nil {
+			t.Errorf("ERROR clearing the email address was accepted.")
+		}
+		if _, emptyFail := fixture.dut.Update(context.Background(), SubscriberUpdate{Index: 1}); emptyFail == nil {
+			t.Errorf("ERROR an update without fields was accepted.")
+		}
+	})
 }
 
 func TestDeleteModel(t *testing.T) {
-	fixture := setupSubscriberModelTestFixture()
-	index := rand.Intn(len(fixture.expectedRecords)) + 1
-	_, deleteFail := fixture.dut.delete(uint8(index))
-	if deleteFail != nil {
-		t.Errorf("ERROR deleting database records. %s", deleteFail.Error())
-	}
-	fetchedRecords, listFail := fixture.dut.list()
-	if listFail != nil {
-		t.Errorf("ERROR fetching database records. %s", listFail.Error())
-	}
-	updatedExpectedRecords := fixture.expectedRecords[1:]
-	if index > 1 {
-		updatedExpectedRecords = append(fixture.expectedRecords[:index-1], fixture.expectedRecords[index-1+1:]...)
-	}
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		index := rand.Intn(len(fixture.expectedRecords)) + 1
+		_, deleteFail := fixture.dut.Delete(context.Background(), uint64(index))
+		if deleteFail != nil {
+			t.Errorf("ERROR deleting database records. %s", deleteFail.Error())
+		}
+		fetchedRecords, listFail := fixture.dut.List(context.Background(), ListQuery{})
+		if listFail != nil {
+			t.Errorf("ERROR fetching database records. %s", listFail.Error())
+		}
+		updatedExpectedRecords := fixture.expectedRecords[1:]
+		if index > 1 {
+			updatedExpectedRecords = append(fixture.expectedRecords[:index-1], fixture.expectedRecords[index-1+1:]...)
+		}
 
-	for index, updatedExpectedRecord := range updatedExpectedRecords {
-		if updatedExpectedRecord != fetchedRecords[index] {
-			t.Errorf("ERROR fetching database records. Expected %v != Actual %v",
-				updatedExpectedRecord, fetchedRecords[index])
+		for index, updatedExpectedRecord := range updatedExpectedRecords {
+			if updatedExpectedRecord != unstamped(fetchedRecords[index]) {
+				t.Errorf("ERROR fetching database records. Expected %v != Actual %v",
+					updatedExpectedRecord, fetchedRecords[index])
+			}
 		}
-	}
-	fixture.tearDown()
+	})
 }
 
 func TestActivateModel(t *testing.T) {
-	fixture := setupSubscriberModelTestFixture()
-	index := rand.Intn(len(fixture.expectedRecords)) + 1
-	activate := rand.Intn(1) == 1
-	_, activateFail := fixture.dut.activate(uint8(index), activate)
-	if activateFail != nil {
-		t.Errorf("ERROR activating a subscriber. %s", activateFail.Error())
-	}
-	fetchedRecords, listFail := fixture.dut.list()
-	if listFail != nil {
-		t.Errorf("ERROR fetching database records. %s", listFail.Error())
-	}
-	fixture.expectedRecords[index-1].ActivationFlag = activate
-	for index, expectedRecord := range fixture.expectedRecords {
-		if expectedRecord != fetchedRecords[index] {
-			t.Errorf("ERROR fetching database records. Expected %v != Actual %v",
-				expectedRecord, fetchedRecords[index])
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		index := rand.Intn(len(fixture.expectedRecords)) + 1
+		activate := rand.Intn(1) == 1
+		_, activateFail := fixture.dut.Activate(context.Background(), uint64(index), activate)
+		if activateFail != nil {
+			t.Errorf("ERROR activating a subscriber. %s", activateFail.Error())
 		}
-	}
-	fixture.tearDown()
+		fetchedRecords, listFail := fixture.dut.List(context.Background(), ListQuery{})
+		if listFail != nil {
+			t.Errorf("ERROR fetching database records. %s", listFail.Error())
+		}
+		fixture.expectedRecords[index-1].ActivationFlag = activate
+		for index, expectedRecord := range fixture.expectedRecords {
+			if expectedRecord != unstamped(fetchedRecords[index]) {
+				t.Errorf("ERROR fetching database records. Expected %v != Actual %v",
+					expectedRecord, fetchedRecords[index])
+			}
+		}
+	})
+}
+
+func TestModelHonorsContext(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		expiredContext, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		if _, listFail := fixture.dut.List(expiredContext, ListQuery{}); !errors.Is(listFail, context.DeadlineExceeded) {
+			t.Errorf("ERROR expected %v when listing with an expired context, got %v", context.DeadlineExceeded, listFail)
+		}
+		if _, retrieveFail := fixture.dut.Retrieve(expiredContext, 1); !errors.Is(retrieveFail, context.DeadlineExceeded) {
+			t.Errorf("ERROR expected %v when retrieving with an expired context, got %v", context.DeadlineExceeded, retrieveFail)
+		}
+		canceledContext, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, deleteFail := fixture.dut.Delete(canceledContext, 1); !errors.Is(deleteFail, context.Canceled) {
+			t.Errorf("ERROR expected %v when deleting with a canceled context, got %v", context.Canceled, deleteFail)
+		}
+		if _, retrieveFail := fixture.dut.Retrieve(context.Background(), 1); retrieveFail != nil {
+			t.Errorf("ERROR record was deleted with a canceled context. %v", retrieveFail)
+		}
+	})
+}
+
+func TestModelReportsStoreErrors(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		_, createFail := fixture.dut.Create(context.Background(), Subscriber{EmailAddress: fixture.expectedRecords[0].EmailAddress})
+		if !errors.Is(createFail, ErrDuplicateEmail) || !isDuplicateEntry(createFail) {
+			t.Errorf("ERROR expected a duplicate entry error on create, got %v", createFail)
+		}
+		emailAddress := fixture.expectedRecords[0].EmailAddress
+		_, updateFail := fixture.dut.Update(context.Background(), SubscriberUpdate{Index: 2, EmailAddress: &emailAddress})
+		if !errors.Is(updateFail, ErrDuplicateEmail) {
+			t.Errorf("ERROR expected a duplicate entry error on update, got %v", updateFail)
+		}
+		lastName := fixture.expectedRecords[1].LastName
+		result, updateFail := fixture.dut.Update(context.Background(), SubscriberUpdate{Index: 2, LastName: &lastName})
+		if updateFail != nil {
+			t.Fatalf("ERROR updating a subscriber without changes. %s", updateFail.Error())
+		}
+		if rowsAffected, _ := result.RowsAffected(); 1 != rowsAffected {
+			t.Errorf("ERROR an update without changes affected %d rows, expected the 1 row found.", rowsAffected)
+		}
+		if isDuplicateEntry(errors.New("Duplicate entry")) {
+			t.Errorf("ERROR an unrelated error was taken for a duplicate entry.")
+		}
+		if _, retrieveFail := fixture.dut.Retrieve(context.Background(), 200); !errors.Is(retrieveFail, ErrNotFound) ||
+			!errors.Is(retrieveFail, sql.ErrNoRows) {
+			t.Errorf("ERROR expected %v when retrieving a missing subscriber, got %v", ErrNotFound, retrieveFail)
+		}
+		if _, listFail := fixture.dut.List(context.Background(), ListQuery{Sort: "password"}); !errors.As(listFail,
+			&ValidationError{}) {
+			t.Errorf("ERROR expected a validation error when sorting by an unknown field, got %v", listFail)
+		}
+	})
 }
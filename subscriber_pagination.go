@@ -0,0 +1,215 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The page sizes of a controller whose configuration leaves them out.
+const (
+	defaultPageSize    = 20
+	defaultMaxPageSize = 100
+)
+
+type Page struct {
+	Subscribers []Subscriber `json:"subscribers" xml:"subscribers>subscriber" yaml:"subscribers"`
+	Total       int          `json:"total" xml:"total" yaml:"total"`
+	Limit       int          `json:"limit" xml:"limit" yaml:"limit"`
+	Offset      *int         `json:"offset,omitempty" xml:"offset,omitempty" yaml:"offset,omitempty"`
+	NextCursor  string       `json:"next_cursor,omitempty" xml:"next_cursor,omitempty" yaml:"next_cursor,omitempty"`
+	PrevCursor  string       `json:"prev_cursor,omitempty" xml:"prev_cursor,omitempty" yaml:"prev_cursor,omitempty"`
+	Links       PageLinks    `json:"links" xml:"links" yaml:"links"`
+}
+
+type PageLinks struct {
+	Self  string `json:"self" xml:"self" yaml:"self"`
+	First string `json:"first" xml:"first" yaml:"first"`
+	Next  string `json:"next,omitempty" xml:"next,omitempty" yaml:"next,omitempty"`
+	Prev  string `json:"prev,omitempty" xml:"prev,omitempty" yaml:"prev,omitempty"`
+}
+
+// Cursors are opaque to clients. They encode an exclusive keyset bound on the index, e.g. "after:42", or on the public
+// ID of the subscriber when indexes are kept private.
+func encodeCursor(direction string, index uint64) string {
+	return encodeCursorKey(direction, strconv.FormatUint(index, 10))
+}
+
+func encodeCursorKey(direction string, key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(direction + ":" + key))
+}
+
+// decodeCursor finds the index that the key of the cursor stands for with subscriberIndex.
+func decodeCursor(cursor string, query *ListQuery, subscriberIndex func(key string) (uint64, error)) error {
+	decoded, decodeError := base64.RawURLEncoding.DecodeString(cursor)
+	if decodeError != nil {
+		return ValidationError{"cursor", "Invalid cursor."}
+	}
+	separator := strings.IndexByte(string(decoded), ':')
+	if separator < 0 {
+		return ValidationError{"cursor", "Invalid cursor."}
+	}
+	index, indexError := subscriberIndex(string(decoded[separator+1:]))
+	var validationError ValidationError
+	if errors.Is(indexError, ErrNotFound) || errors.As(indexError, &validationError) {
+		return ValidationError{"cursor", "Invalid cursor."}
+	}
+	if indexError != nil {
+		return indexError
+	}
+	switch string(decoded[:separator]) {
+	case "after":
+		query.After = index
+	case "before":
+		query.Before = index
+	default:
+		return ValidationError{"cursor", "Invalid cursor."}
+	}
+	return nil
+}
+
+// parsePageQuery reads limit, offset and cursor from the query string. A page is either offset based or cursor based.
+func parsePageQuery(values url.Values, pageSize int, maxPageSize int,
+	subscriberIndex func(key string) (uint64, error)) (ListQuery, bool, error) {
+	query := ListQuery{Limit: pageSize}
+	if limit := values.Get("limit"); "" != limit {
+		parsedLimit, limitError := strconv.Atoi(limit)
+		if limitError != nil || parsedLimit < 1 {
+			return query, false, ValidationError{"limit", "Please set limit to a positive number."}
+		}
+		query.Limit = parsedLimit
+	}
+	if query.Limit > maxPageSize {
+		return query, false, ValidationError{"limit", "Please set limit to at most " + strconv.Itoa(maxPageSize) + "."}
+	}
+	cursor, cursorMode := values["cursor"]
+	offset := values.Get("offset")
+	if cursorMode && "" != offset {
+		return query, false, ValidationError{"offset", "Please provide either an offset or a cursor, not both."}
+	}
+	if cursorMode {
+		return query, true, decodeCursor(cursor[0], &query, subscriberIndex)
+	}
+	if "" != offset {
+		parsedOffset, offsetError := strconv.Atoi(offset)
+		if offsetError != nil || parsedOffset < 0 {
+			return query, false, ValidationError{"offset", "Please set offset to zero or a positive number."}
+		}
+		query.Offset = parsedOffset
+	}
+	return query, false, nil
+}
+
+// parseListFilters reads the activation_flag, deleted, email_domain, name_prefix and updated_since filters and the sort
+// field and direction.
+func parseListFilters(values url.Values, query *ListQuery) error {
+	switch values.Get("activation_flag") {
+	case "":
+	case "true":
+		activated := true
+		query.ActivationFlag = &activated
+	case "false":
+		deactivated := false
+		query.ActivationFlag = &deactivated
+	default:
+		return ValidationError{"activation_flag", "Please set activation_flag to true or false."}
+	}
+	switch values.Get("deleted") {
+	case "", "false":
+	case "true":
+		query.Deleted = true
+	default:
+		return ValidationError{"deleted", "Please set deleted to true or false."}
+	}
+	query.EmailDomain = strings.TrimPrefix(values.Get("email_domain"), "@")
+	query.NamePrefix = values.Get("name_prefix")
+	if updatedSince := values.Get("updated_since"); "" != updatedSince {
+		parsedTime, timeError := time.Parse(time.RFC3339Nano, updatedSince)
+		if timeError != nil {
+			return ValidationError{"updated_since", "Please set updated_since to an RFC 3339 time, e.g. 2021-06-01T00:00:00Z."}
+		}
+		query.UpdatedSince = parsedTime
+	}
+	query.Sort = values.Get("sort")
+	if "" != query.Sort && !sortableField(query.Sort) {
+		return ValidationError{"sort", "Please sort by one of " + strings.Join(listSortFields, ", ") + "."}
+	}
+	switch values.Get("direction") {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return ValidationError{"direction", "Please set direction to asc or desc."}
+	}
+	return query.validate()
+}
+
+// makePage links the pages around the subscribers. With publicIDs, the cursors are keyed on public IDs.
+func makePage(subscribers []Subscriber, total int, query ListQuery, cursorMode bool, hasMore bool, publicIDs bool,
+	location url.URL) Page {
+	page := Page{Subscribers: subscribers, Total: total, Limit: query.Limit}
+	link := func(change func(values url.Values)) string {
+		values := location.Query()
+		values.Del("offset")
+		values.Del("cursor")
+		values.Set("limit", strconv.Itoa(query.Limit))
+		change(values)
+		linked := location
+		linked.RawQuery = values.Encode()
+		return linked.RequestURI()
+	}
+	page.Links.Self = location.RequestURI()
+	page.Links.First = link(func(url.Values) {})
+	if 0 != len(subscribers) && query.orderedByIndex() {
+		last, first := subscribers[len(subscribers)-1], subscribers[0]
+		if publicIDs {
+			page.NextCursor, page.PrevCursor = encodeCursorKey("after", last.PublicID), encodeCursorKey("before", first.PublicID)
+		} else {
+			page.NextCursor, page.PrevCursor = encodeCursor("after", last.Index), encodeCursor("before", first.Index)
+		}
+	}
+	if cursorMode {
+		if 0 != len(subscribers) && (hasMore || 0 != query.Before) {
+			page.Links.Next = link(func(values url.Values) { values.Set("cursor", page.NextCursor) })
+		}
+		if 0 != len(subscribers) && (hasMore || 0 != query.After) {
+			page.Links.Prev = link(func(values url.Values) { values.Set("cursor", page.PrevCursor) })
+		}
+		return page
+	}
+	offset := query.Offset
+	page.Offset = &offset
+	if query.Offset+query.Limit < total {
+		page.Links.Next = link(func(values url.Values) { values.Set("offset", strconv.Itoa(query.Offset+query.Limit)) })
+	}
+	if query.Offset > 0 {
+		previous := query.Offset - query.Limit
+		if previous < 0 {
+			previous = 0
+		}
+		page.Links.Prev = link(func(values url.Values) { values.Set("offset", strconv.Itoa(previous)) })
+	}
+	return page
+}
+
+func (links PageLinks) header() string {
+	var relations []string
+	for _, relation := range []struct {
+		name string
+		link string
+	}{{"first", links.First}, {"prev", links.Prev}, {"next", links.Next}} {
+		if "" != relation.link {
+			relations = append(relations, "<"+relation.link+`>; rel="`+relation.name+`"`)
+		}
+	}
+	return strings.Join(relations, ", ")
+}
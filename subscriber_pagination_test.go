@@ -0,0 +1,240 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListQuery(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		for _, testCase := range []struct {
+			query    ListQuery
+			expected []Subscriber
+		}{
+			{ListQuery{Limit: 2}, fixture.expectedRecords[:2]},
+			{ListQuery{Limit: 2, Offset: 2}, fixture.expectedRecords[2:]},
+			{ListQuery{Limit: 2, Offset: 5}, []Subscriber{}},
+			{ListQuery{Limit: 1, After: 1}, fixture.expectedRecords[1:2]},
+			{ListQuery{Limit: 1, Before: 3}, fixture.expectedRecords[1:2]},
+			{ListQuery{Before: 3}, fixture.expectedRecords[:2]},
+		} {
+			fetchedRecords, listFail := fixture.dut.List(context.Background(), testCase.query)
+			if listFail != nil {
+				t.Fatalf("ERROR listing %v. %s", testCase.query, listFail.Error())
+			}
+			if ConvertToJson(testCase.expected) != ConvertToJson(unstampedList(fetchedRecords)) {
+				t.Errorf("ERROR listing %v. Expected %v != Actual %v", testCase.query, testCase.expected, fetchedRecords)
+			}
+		}
+		count, countFail := fixture.dut.Count(context.Background(), ListQuery{Limit: 1})
+		if countFail != nil || len(fixture.expectedRecords) != count {
+			t.Errorf("ERROR expected a count of %d, got %d. %v", len(fixture.expectedRecords), count, countFail)
+		}
+		if _, listFail := fixture.dut.List(context.Background(), ListQuery{Limit: 1, Offset: 1, After: 1}); listFail == nil {
+			t.Errorf("ERROR an offset combined with a cursor was accepted.")
+		}
+	})
+}
+
+func TestListQueryFilters(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		if _, activateFail := fixture.dut.Activate(context.Background(), 3, true); activateFail != nil {
+			t.Fatalf("ERROR activating database record. %s", activateFail.Error())
+		}
+		if _, createFail := fixture.dut.Create(context.Background(),
+			Subscriber{EmailAddress: "percent%under_score@100%.com", FirstName: "100%", LastName: "a_b"}); createFail != nil {
+			t.Fatalf("ERROR creating database record. %s", createFail.Error())
+		}
+		activated, deactivated := true, false
+		for _, testCase := range []struct {
+			query    ListQuery
+			expected []uint64
+		}{
+			{ListQuery{ActivationFlag: &activated}, []uint64{3}},
+			{ListQuery{ActivationFlag: &deactivated}, []uint64{1, 2, 4}},
+			{ListQuery{EmailDomain: "EMAIL.com"}, []uint64{2, 3}},
+			{ListQuery{EmailDomain: "100%.com"}, []uint64{4}},
+			{ListQuery{EmailDomain: "1000.com"}, []uint64{}},
+			{ListQuery{NamePrefix: "marc"}, []uint64{1, 2}},
+			{ListQuery{NamePrefix: "andr"}, []uint64{3}},
+			{ListQuery{NamePrefix: "a_"}, []uint64{4}},
+			{ListQuery{NamePrefix: "ab"}, []uint64{}},
+			{ListQuery{NamePrefix: "marc", EmailDomain: "gmail.com"}, []uint64{1}},
+			{ListQuery{Sort: "first_name"}, []uint64{4, 3, 2, 1}},
+			{ListQuery{Sort: "last_name", Descending: true}, []uint64{2, 1, 3, 4}},
+			{ListQuery{Sort: "index", Descending: true, Limit: 2, Offset: 1}, []uint64{3, 2}},
+			{ListQuery{Sort: "email_address", ActivationFlag: &deactivated, Limit: 2}, []uint64{2, 1}},
+		} {
+			fetchedRecords, listFail := fixture.dut.List(context.Background(), testCase.query)
+			if listFail != nil {
+				t.Fatalf("ERROR listing %+v. %s", testCase.query, listFail.Error())
+			}
+			indexes := make([]uint64, 0, len(fetchedRecords))
+			for _, subscriber := range fetchedRecords {
+				indexes = append(indexes, subscriber.Index)
+			}
+			if ConvertToJson(testCase.expected) != ConvertToJson(indexes) {
+				t.Errorf("ERROR listing %+v. Expected %v != Actual %v", testCase.query, testCase.expected, indexes)
+			}
+			testCase.query.Limit, testCase.query.Offset = 0, 0
+			count, countFail := fixture.dut.Count(context.Background(), testCase.query)
+			if allRecords, _ := fixture.dut.List(context.Background(), testCase.query); countFail != nil || len(allRecords) != count {
+				t.Errorf("ERROR counting %+v. Expected %d != Actual %d. %v", testCase.query, len(allRecords), count, countFail)
+			}
+		}
+		for _, query := range []ListQuery{{Sort: "password"}, {Sort: "last_name", After: 1}, {Descending: true, Before: 2}} {
+			if _, listFail := fixture.dut.List(context.Background(), query); listFail == nil {
+				t.Errorf("ERROR listing %+v was accepted.", query)
+			}
+		}
+	})
+}
+
+func listPage(t *testing.T, controller SubscriberController, target string) (*httptest.ResponseRecorder, Page) {
+	request, fault := http.NewRequest("GET", target, nil)
+	if fault != nil {
+		t.Fatal(fault)
+	}
+	response := httptest.NewRecorder()
+	http.HandlerFunc(controller.list).ServeHTTP(response, request)
+	var page Page
+	if http.StatusOK == response.Code {
+		if jsonError := json.Unmarshal(response.Body.Bytes(), &page); jsonError != nil {
+			t.Fatalf("handler returned an invalid page %s. %s", response.Body.String(), jsonError.Error())
+		}
+	}
+	return response, page
+}
+
+func TestListControllerPaginates(t *testing.T) {
+	model := MakeMemoryRecords()
+	if _, createFail := model.CreateBatch(context.Background(), makeBulkSubscribers(25)); createFail != nil {
+		t.Fatal(createFail)
+	}
+	controller := makeSubscriberController(model, MVCConfiguration{PageSize: 10, MaxPageSize: 20})
+
+	response, page := listPage(t, controller, "/subscribers")
+	if 10 != len(page.Subscribers) || 25 != page.Total || 10 != page.Limit || nil == page.Offset || 0 != *page.Offset {
+		t.Errorf("handler returned unexpected first page: %s", response.Body.String())
+	}
+	if "/subscribers?limit=10&offset=10" != page.Links.Next || "" != page.Links.Prev {
+		t.Errorf("handler returned unexpected links: %v", page.Links)
+	}
+	if link := response.Header().Get("Link"); !strings.Contains(link, `</subscribers?limit=10&offset=10>; rel="next"`) {
+		t.Errorf("handler returned unexpected Link header: %s", link)
+	}
+
+	response, page = listPage(t, controller, "/subscribers?offset=20")
+	if 5 != len(page.Subscribers) || 21 != page.Subscribers[0].Index || "" != page.Links.Next ||
+		"/subscribers?limit=10&offset=10" != page.Links.Prev {
+		t.Errorf("handler returned unexpected last page: %s", response.Body.String())
+	}
+
+	_, page = listPage(t, controller, "/subscribers?limit=5&cursor="+url.QueryEscape(encodeCursor("after", 5)))
+	if 5 != len(page.Subscribers) || 6 != page.Subscribers[0].Index || nil != page.Offset || "" == page.Links.Next {
+		t.Fatalf("handler returned unexpected cursor page: %v", page)
+	}
+	nextResponse, nextPage := listPage(t, controller, page.Links.Next)
+	if 11 != nextPage.Subscribers[0].Index {
+		t.Errorf("handler returned unexpected next page: %s", nextResponse.Body.String())
+	}
+	previousResponse, previousPage := listPage(t, controller, nextPage.Links.Prev)
+	if 6 != previousPage.Subscribers[0].Index || 10 != previousPage.Subscribers[4].Index {
+		t.Errorf("handler returned unexpected previous page: %s", previousResponse.Body.String())
+	}
+	_, lastPage := listPage(t, controller, "/subscribers?cursor="+encodeCursor("after", 20))
+	if 5 != len(lastPage.Subscribers) || "" != lastPage.Links.Next || "" == lastPage.Links.Prev {
+		t.Errorf("handler returned unexpected last cursor page: %v", lastPage)
+	}
+
+	for _, target := range []string{
+		"/subscribers?limit=21",
+		"/subscribers?limit=0",
+		"/subscribers?offset=-1",
+		"/subscribers?offset=1&cursor=" + encodeCursor("after", 1),
+		"/subscribers?cursor=bogus",
+		"/subscribers?cursor=" + encodeCursor("sideways", 1),
+	} {
+		if response, _ := listPage(t, controller, target); http.StatusBadRequest != response.Code {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v", target, response.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestListControllerDefaultPageSizes(t *testing.T) {
+	model := MakeMemoryRecords()
+	if _, createFail := model.CreateBatch(context.Background(), makeBulkSubscribers(25)); createFail != nil {
+		t.Fatal(createFail)
+	}
+	for _, configuration := range []MVCConfiguration{{}, {MaxPageSize: 10}, {PageSize: 5}} {
+		controller := makeSubscriberController(model, configuration)
+		response, page := listPage(t, controller, "/subscribers")
+		expectedLimit := defaultPageSize
+		if 0 != configuration.MaxPageSize {
+			expectedLimit = configuration.MaxPageSize
+		} else if 0 != configuration.PageSize {
+			expectedLimit = configuration.PageSize
+		}
+		expectedNext := "/subscribers?limit=" + strconv.Itoa(expectedLimit) + "&offset=" + strconv.Itoa(expectedLimit)
+		if http.StatusOK != response.Code || expectedLimit != len(page.Subscribers) || 25 != page.Total ||
+			expectedNext != page.Links.Next {
+			t.Errorf("handler returned unexpected first page with %+v: %s", configuration, response.Body.String())
+		}
+		response, page = listPage(t, controller, "/subscribers?limit=1&offset=24")
+		if http.StatusOK != response.Code || 1 != len(page.Subscribers) || 25 != page.Subscribers[0].Index ||
+			"" != page.Links.Next {
+			t.Errorf("handler returned unexpected last page with %+v: %s", configuration, response.Body.String())
+		}
+	}
+}
+
+func TestListControllerFilters(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	if _, activateFail := fixture.model.Activate(context.Background(), 2, true); activateFail != nil {
+		t.Fatal(activateFail)
+	}
+	response, page := listPage(t, fixture.dut, "/subscribers?activation_flag=false&email_domain=%40email.com&limit=1")
+	if 1 != page.Total || 1 != len(page.Subscribers) || 3 != page.Subscribers[0].Index {
+		t.Errorf("handler returned unexpected filtered page: %s", response.Body.String())
+	}
+	response, page = listPage(t, fixture.dut, "/subscribers?sort=last_name&direction=desc&limit=2")
+	if 3 != page.Total || 2 != page.Subscribers[0].Index || 1 != page.Subscribers[1].Index || "" != page.NextCursor ||
+		"/subscribers?direction=desc&limit=2&offset=2&sort=last_name" != page.Links.Next {
+		t.Errorf("handler returned unexpected sorted page: %s", response.Body.String())
+	}
+	time.Sleep(2 * time.Millisecond)
+	since := storeTime()
+	lastName := "Solo"
+	if _, updateFail := fixture.model.Update(context.Background(), SubscriberUpdate{Index: 3, LastName: &lastName}); updateFail != nil {
+		t.Fatal(updateFail)
+	}
+	response, page = listPage(t, fixture.dut, "/subscribers?updated_since="+url.QueryEscape(since.Format(time.RFC3339Nano)))
+	if 1 != page.Total || 3 != page.Subscribers[0].Index {
+		t.Errorf("handler returned unexpected page of updated subscribers: %s", response.Body.String())
+	}
+	for _, target := range []string{
+		"/subscribers?activation_flag=yes",
+		"/subscribers?sort=password",
+		"/subscribers?direction=sideways",
+		"/subscribers?updated_since=yesterday",
+		"/subscribers?sort=first_name&cursor=" + encodeCursor("after", 1),
+	} {
+		if response, _ := listPage(t, fixture.dut, target); http.StatusBadRequest != response.Code {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v", target, response.Code, http.StatusBadRequest)
+		}
+	}
+	fixture.tearDown()
+}
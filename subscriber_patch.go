@@ -0,0 +1,289 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	mergePatchMediaType = "application/merge-patch+json"
+	jsonPatchMediaType  = "application/json-patch+json"
+)
+
+type patchOperation struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// PatchSubscriber applies patch to the JSON representation of a subscriber and stores the changed fields,
+// all in one transaction. Removing first_name or last_name clears them.
+func PatchSubscriber(ctx context.Context, store SubscriberStore, index uint64,
+	patch func(document map[string]interface{}) (map[string]interface{}, error)) (Subscriber, error) {
+	var patched Subscriber
+	transactionFail := store.Transaction(ctx, func(transaction SubscriberStore) error {
+		subscriber, retrieveFail := transaction.Retrieve(ctx, index)
+		if retrieveFail != nil {
+			return retrieveFail
+		}
+		document, patchFail := patch(subscriberDocument(*subscriber))
+		if patchFail != nil {
+			return patchFail
+		}
+		var documentFail error
+		if patched, documentFail = documentSubscriber(document, *subscriber); documentFail != nil {
+			return documentFail
+		}
+		update := SubscriberUpdate{Index: index}
+		if patched.EmailAddress != subscriber.EmailAddress {
+			update.EmailAddress = &patched.EmailAddress
+		}
+		if patched.LastName != subscriber.LastName {
+			update.LastName = &patched.LastName
+		}
+		if patched.FirstName != subscriber.FirstName {
+			update.FirstName = &patched.FirstName
+		}
+		if update.EmailAddress != nil || update.LastName != nil || update.FirstName != nil {
+			if _, updateFail := transaction.Update(ctx, update); updateFail != nil {
+				return updateFail
+			}
+		}
+		if patched.ActivationFlag != subscriber.ActivationFlag {
+			if _, activateFail := transaction.Activate(ctx, index, patched.ActivationFlag); activateFail != nil {
+				return activateFail
+			}
+		}
+		return nil
+	})
+	return patched, transactionFail
+}
+
+func subscriberDocument(subscriber Subscriber) map[string]interface{} {
+	document := map[string]interface{}{
+		"index":           json.Number(strconv.FormatUint(subscriber.Index, 10)),
+		"email_address":   subscriber.EmailAddress,
+		"first_name":      subscriber.FirstName,
+		"last_name":       subscriber.LastName,
+		"activation_flag": subscriber.ActivationFlag,
+	}
+	if "" != subscriber.PublicID {
+		document["id"] = subscriber.PublicID
+	}
+	for field, at := range map[string]*time.Time{"created_at": subscriber.CreatedAt, "updated_at": subscriber.UpdatedAt} {
+		if at != nil {
+			document[field] = at.Format(time.RFC3339Nano)
+		}
+	}
+	return document
+}
+
+// documentSubscriber reads a patched document of the original subscriber. The index, the public ID and the timestamps
+// may be left in the document but cannot be changed.
+func documentSubscriber(document map[string]interface{}, original Subscriber) (Subscriber, error) {
+	index := original.Index
+	subscriber := Subscriber{Index: index, PublicID: original.PublicID}
+	invalid := func(field string, message string) (Subscriber, error) {
+		return subscriber, requestError{http.StatusUnprocessableEntity, field, message}
+	}
+	for field, value := range document {
+		var valid bool
+		switch field {
+		case "index":
+			if number, isNumber := value.(json.Number); !isNumber || strconv.FormatUint(index, 10) != number.String() {
+				return invalid("index", "Subscriber index cannot be changed.")
+			}
+			valid = true
+		case "id", "created_at", "updated_at":
+			if text, isText := value.(string); !isText || text != subscriberDocument(original)[field] {
+				return invalid(field, "Subscriber "+field+" cannot be changed.")
+			}
+			valid = true
+		case "email_address":
+			subscriber.EmailAddress, valid = value.(string)
+		case "first_name":
+			subscriber.FirstName, valid = value.(string)
+		case "last_name":
+			subscriber.LastName, valid = value.(string)
+		case "activation_flag":
+			subscriber.ActivationFlag, valid = value.(bool)
+		default:
+			return invalid(field, "Subscribers have no field "+field+".")
+		}
+		if !valid {
+			return invalid(field, "Subscriber "+field+" has the wrong type.")
+		}
+	}
+	if _, found := document["index"]; !found {
+		return invalid("index", "Subscriber index cannot be changed.")
+	}
+	if "" == subscriber.EmailAddress {
+		return invalid("email_address", "Subscriber email_address is required.")
+	}
+	if _, found := document["activation_flag"]; !found {
+		return invalid("activation_flag", "Subscriber activation_flag is required.")
+	}
+	return subscriber, nil
+}
+
+// decodePatchValue decodes like json.Unmarshal, but keeps numbers as json.Number so that every index compares exactly.
+func decodePatchValue(data []byte, value *interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if decodeError := decoder.Decode(value); decodeError != nil {
+		return decodeError
+	}
+	if _, tokenError := decoder.Token(); tokenError != io.EOF {
+		return errors.New("unexpected data after the JSON value")
+	}
+	return nil
+}
+
+func mergePatch(body []byte) (func(map[string]interface{}) (map[string]interface{}, error), error) {
+	var patch interface{}
+	if decodeError := decodePatchValue(body, &patch); decodeError != nil {
+		return nil, requestError{http.StatusBadRequest, "", "Invalid merge patch. " + decodeError.Error()}
+	}
+	return func(document map[string]interface{}) (map[string]interface{}, error) {
+		merged, isObject := mergeValue(document, patch).(map[string]interface{})
+		if !isObject {
+			return nil, requestError{http.StatusUnprocessableEntity, "", "A subscriber must remain a JSON object."}
+		}
+		return merged, nil
+	}, nil
+}
+
+// mergeValue follows RFC 7386: objects are merged member by member, null removes a member, and any other value
+// replaces the target.
+func mergeValue(target interface{}, patch interface{}) interface{} {
+	patchObject, isObject := patch.(map[string]interface{})
+	if !isObject {
+		return patch
+	}
+	targetObject, isObject := target.(map[string]interface{})
+	if !isObject {
+		targetObject = make(map[string]interface{})
+	}
+	for name, value := range patchObject {
+		if nil == value {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = mergeValue(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
+
+// jsonPatch follows RFC 6902 on the flat subscriber representation, so every path points at one field.
+func jsonPatch(body []byte) (func(map[string]interface{}) (map[string]interface{}, error), error) {
+	var operations []patchOperation
+	decoder := json.NewDecoder(strings.NewReader(string(body)))
+	decoder.DisallowUnknownFields()
+	if decodeError := decoder.Decode(&operations); decodeError != nil {
+		return nil, requestError{http.StatusBadRequest, "", "Invalid JSON patch. " + decodeError.Error()}
+	}
+	for _, operation := range operations {
+		if operationError := operation.validate(); operationError != nil {
+			return nil, operationError
+		}
+	}
+	return func(document map[string]interface{}) (map[string]interface{}, error) {
+		for _, operation := range operations {
+			if operationFail := operation.apply(document); operationFail != nil {
+				return nil, operationFail
+			}
+		}
+		return document, nil
+	}, nil
+}
+
+func (operation patchOperation) validate() error {
+	invalid := func(message string) error {
+		return requestError{http.StatusBadRequest, "", "Invalid JSON patch operation " + operation.Op + ". " + message}
+	}
+	switch operation.Op {
+	case "add", "replace", "test":
+		if nil == operation.Value {
+			return invalid("A value is required.")
+		}
+	case "move", "copy":
+		if nil == operation.From {
+			return invalid("A from path is required.")
+		}
+		if _, pointerError := patchField(*operation.From); pointerError != nil {
+			return pointerError
+		}
+	case "remove":
+	default:
+		return invalid("Supported operations are add, remove, replace, move, copy and test.")
+	}
+	if nil == operation.Path {
+		return invalid("A path is required.")
+	}
+	_, pointerError := patchField(*operation.Path)
+	return pointerError
+}
+
+func (operation patchOperation) apply(document map[string]interface{}) error {
+	field, _ := patchField(*operation.Path)
+	missing := func(field string) error {
+		return requestError{http.StatusUnprocessableEntity, field, "Subscriber field " + field + " does not exist."}
+	}
+	var value interface{}
+	if nil != operation.Value {
+		if decodeError := decodePatchValue(*operation.Value, &value); decodeError != nil {
+			return requestError{http.StatusBadRequest, "", "Invalid JSON patch value. " + decodeError.Error()}
+		}
+	}
+	switch operation.Op {
+	case "add":
+		document[field] = value
+	case "remove", "replace":
+		if _, found := document[field]; !found {
+			return missing(field)
+		}
+		if "remove" == operation.Op {
+			delete(document, field)
+		} else {
+			document[field] = value
+		}
+	case "move", "copy":
+		from, _ := patchField(*operation.From)
+		fromValue, found := document[from]
+		if !found {
+			return missing(from)
+		}
+		if "move" == operation.Op {
+			delete(document, from)
+		}
+		document[field] = fromValue
+	case "test":
+		if current, found := document[field]; !found || !reflect.DeepEqual(current, value) {
+			return requestError{http.StatusConflict, field, "Subscriber field " + field + " does not have the tested value."}
+		}
+	}
+	return nil
+}
+
+func patchField(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") || strings.Contains(pointer[1:], "/") {
+		return "", requestError{http.StatusUnprocessableEntity, "",
+			"JSON patch path " + pointer + " does not point at a subscriber field."}
+	}
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:]), nil
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestPatchSubscriber(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		patch, patchError := mergePatch([]byte(`{"first_name": "Luke", "last_name": null, "activation_flag": true}`))
+		if patchError != nil {
+			t.Fatal(patchError)
+		}
+		patched, patchFail := PatchSubscriber(context.Background(), fixture.dut, 1, patch)
+		if patchFail != nil {
+			t.Fatalf("ERROR patching a subscriber. %s", patchFail.Error())
+		}
+		expected := Subscriber{Index: 1, EmailAddress: fixture.expectedRecords[0].EmailAddress, FirstName: "Luke",
+			ActivationFlag: true}
+		if subscriber, _ := fixture.dut.Retrieve(context.Background(), 1); expected != unstamped(patched) || expected != unstamped(*subscriber) {
+			t.Errorf("ERROR expected %v != Actual %v and %v", expected, patched, *subscriber)
+		}
+
+		patch, _ = jsonPatch([]byte(`[{"op": "replace", "path": "/activation_flag", "value": false},
+			{"op": "replace", "path": "/email_address", "value": "` + fixture.expectedRecords[1].EmailAddress + `"}]`))
+		if _, patchFail = PatchSubscriber(context.Background(), fixture.dut, 1, patch); patchFail == nil {
+			t.Errorf("ERROR a duplicate email address was patched.")
+		}
+		if subscriber, _ := fixture.dut.Retrieve(context.Background(), 1); expected != unstamped(*subscriber) {
+			t.Errorf("ERROR a failed patch was not rolled back. Expected %v != Actual %v", expected, *subscriber)
+		}
+		if _, patchFail = PatchSubscriber(context.Background(), fixture.dut, 200, patch); !errors.Is(patchFail, sql.ErrNoRows) {
+			t.Errorf("ERROR expected %v when patching a missing subscriber, got %v", sql.ErrNoRows, patchFail)
+		}
+	})
+}
+
+func TestPatchDocuments(t *testing.T) {
+	subscriber := Subscriber{Index: 7, EmailAddress: "rey@starwars.com", FirstName: "Rey", LastName: "Palpatine"}
+	for _, testCase := range []struct {
+		mediaType      string
+		body           string
+		expected       Subscriber
+		expectedStatus int
+	}{
+		{mergePatchMediaType, `{"last_name": "Skywalker", "activation_flag": true}`,
+			Subscriber{Index: 7, EmailAddress: "rey@starwars.com", FirstName: "Rey", LastName: "Skywalker", ActivationFlag: true}, 0},
+		{mergePatchMediaType, `{"first_name": null}`, Subscriber{Index: 7, EmailAddress: "rey@starwars.com", LastName: "Palpatine"}, 0},
+		{mergePatchMediaType, `{"email_address": null}`, Subscriber{}, http.StatusUnprocessableEntity},
+		{mergePatchMediaType, `{"index": 8}`, Subscriber{}, http.StatusUnprocessableEntity},
+		{mergePatchMediaType, `{"password": "secret"}`, Subscriber{}, http.StatusUnprocessableEntity},
+		{mergePatchMediaType, `{"activation_flag": "yes"}`, Subscriber{}, http.StatusUnprocessableEntity},
+		{mergePatchMediaType, `[]`, Subscriber{}, http.StatusUnprocessableEntity},
+		{mergePatchMediaType, `{`, Subscriber{}, http.StatusBadRequest},
+		{jsonPatchMediaType, `[{"op": "test", "path": "/first_name", "value": "Rey"},
+			{"op": "copy", "from": "/first_name", "path": "/last_name"},
+			{"op": "replace", "path": "/activation_flag", "value": true}]`,
+			Subscriber{Index: 7, EmailAddress: "rey@starwars.com", FirstName: "Rey", LastName: "Rey", ActivationFlag: true}, 0},
+		{jsonPatchMediaType, `[{"op": "move", "from": "/first_name", "path": "/last_name"}]`,
+			Subscriber{Index: 7, EmailAddress: "rey@starwars.com", LastName: "Rey"}, 0},
+		{jsonPatchMediaType, `[{"op": "remove", "path": "/last_name"}, {"op": "add", "path": "/first_name", "value": "Kira"}]`,
+			Subscriber{Index: 7, EmailAddress: "rey@starwars.com", FirstName: "Kira"}, 0},
+		{jsonPatchMediaType, `[{"op": "test", "path": "/first_name", "value": "Finn"}]`, Subscriber{}, http.StatusConflict},
+		{jsonPatchMediaType, `[{"op": "remove", "path": "/last_name"}, {"op": "remove", "path": "/last_name"}]`,
+			Subscriber{}, http.StatusUnprocessableEntity},
+		{jsonPatchMediaType, `[{"op": "replace", "path": "/names/0", "value": "Rey"}]`, Subscriber{},
+			http.StatusUnprocessableEntity},
+		{jsonPatchMediaType, `[{"op": "increment", "path": "/index"}]`, Subscriber{}, http.StatusBadRequest},
+		{jsonPatchMediaType, `[{"op": "add", "path": "/first_name"}]`, Subscriber{}, http.StatusBadRequest},
+		{jsonPatchMediaType, `{"op": "add", "path": "/first_name", "value": "Rey"}`, Subscriber{}, http.StatusBadRequest},
+	} {
+		makePatch := mergePatch
+		if jsonPatchMediaType == testCase.mediaType {
+			makePatch = jsonPatch
+		}
+		patched, patchFail := func() (Subscriber, error) {
+			patch, patchError := makePatch([]byte(testCase.body))
+			if patchError != nil {
+				return Subscriber{}, patchError
+			}
+			document, patchFail := patch(subscriberDocument(subscriber))
+			if patchFail != nil {
+				return Subscriber{}, patchFail
+			}
+			return documentSubscriber(document, subscriber)
+		}()
+		var fault requestError
+		if errors.As(patchFail, &fault) && testCase.expectedStatus != fault.status {
+			t.Errorf("ERROR patching with %s returned status %d, expected %d. %s", testCase.body, fault.status,
+				testCase.expectedStatus, fault.message)
+		}
+		if patchFail == nil && (0 != testCase.expectedStatus || testCase.expected != patched) {
+			t.Errorf("ERROR patching with %s. Expected %v != Actual %v", testCase.body, testCase.expected, patched)
+		}
+	}
+}
+
+func TestPatchController(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	response := sendBody(fixture.dut.patch, "PATCH", "/subscribers/2", "2", mergePatchMediaType,
+		`{"first_name": "Marco", "activation_flag": true}`)
+	expectedMessage := ConvertToJson(Update{"Record patched", Subscriber{Index: 2,
+		EmailAddress: fixture.expectedRecords[1].EmailAddress, FirstName: "Marco", LastName: "Concepcion", ActivationFlag: true}})
+	if status := response.Code; status != http.StatusOK || expectedMessage != unstampedJson(response.Body.String()) {
+		t.Errorf("handler returned unexpected response: got %v %s want %s", status, response.Body.String(), expectedMessage)
+	}
+	response = sendBody(fixture.dut.patch, "PATCH", "/subscribers/2", "2", jsonPatchMediaType,
+		`[{"op": "replace", "path": "/activation_flag", "value": false}]`)
+	if subscriber, _ := fixture.model.Retrieve(context.Background(), 2); http.StatusOK != response.Code ||
+		subscriber.ActivationFlag {
+		t.Errorf("handler did not deactivate the subscriber: got %v %s", response.Code, response.Body.String())
+	}
+	response = sendBody(fixture.dut.patch, "PATCH", "/subscribers/3", "3", "application/json", `{"activation_flag": true}`)
+	if subscriber, _ := fixture.model.Retrieve(context.Background(), 3); http.StatusOK != response.Code ||
+		!subscriber.ActivationFlag {
+		t.Errorf("handler did not activate the subscriber: got %v %s", response.Code, response.Body.String())
+	}
+	for _, testCase := range []struct {
+		index          string
+		mediaType      string
+		body           string
+		expectedStatus int
+	}{
+		{"200", mergePatchMediaType, `{"first_name": "Nobody"}`, http.StatusNotFound},
+		{"1", mergePatchMediaType, `{"email_address": ""}`, http.StatusUnprocessableEntity},
+		{"1", jsonPatchMediaType, `[{"op": "test", "path": "/activation_flag", "value": true}]`, http.StatusConflict},
+		{"1", mergePatchMediaType, `{"updated_at": "2021-06-01T00:00:00Z"}`, http.StatusUnprocessableEntity},
+		{"1", jsonPatchMediaType, `not json`, http.StatusBadRequest},
+	} {
+		response = sendBody(fixture.dut.patch, "PATCH", "/subscribers/"+testCase.index, testCase.index, testCase.mediaType,
+			testCase.body)
+		if status := response.Code; status != testCase.expectedStatus {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v", testCase.body, status,
+				testCase.expectedStatus)
+		}
+	}
+	if subscriber, _ := fixture.model.Retrieve(context.Background(), 1); fixture.expectedRecords[0] != unstamped(*subscriber) {
+		t.Errorf("ERROR rejected patches changed subscriber %v", *subscriber)
+	}
+	fixture.tearDown()
+}
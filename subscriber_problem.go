@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+)
+
+const problemMediaType = "application/problem+json"
+
+// Problem is an error response as described by RFC 7807. Errors lists the fields at fault of a validation problem.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Problems without a type of their own are about:blank and titled after their status code.
+const (
+	ProblemValidation     = "urn:subscribers:problem:validation"
+	ProblemNotFound       = "urn:subscribers:problem:not-found"
+	ProblemDuplicateEmail = "urn:subscribers:problem:duplicate-email"
+	ProblemDeletedEmail   = "urn:subscribers:problem:deleted-email"
+	ProblemConflict       = "urn:subscribers:problem:conflict"
+	ProblemUnavailable    = "urn:subscribers:problem:unavailable"
+	ProblemTimeout        = "urn:subscribers:problem:timeout"
+	ProblemPrecondition   = "urn:subscribers:problem:precondition-failed"
+)
+
+var problemTitles = map[string]string{
+	ProblemValidation:     "The subscriber or its query is not valid.",
+	ProblemNotFound:       "The subscriber does not exist.",
+	ProblemDuplicateEmail: "The email address belongs to another subscriber.",
+	ProblemDeletedEmail:   "The email address belongs to a deleted subscriber.",
+	ProblemConflict:       "The subscriber cannot be changed this way right now.",
+	ProblemUnavailable:    "The database is unavailable.",
+	ProblemTimeout:        "The database did not respond in time.",
+	ProblemPrecondition:   "The subscriber does not have the version the request was meant for.",
+}
+
+func makeProblem(status int, detail string) Problem {
+	return Problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+func makeTypedProblem(problemType string, status int, detail string) Problem {
+	return Problem{Type: problemType, Title: problemTitles[problemType], Status: status, Detail: detail}
+}
+
+func makeValidationProblem(status int, field string, detail string) Problem {
+	problem := makeTypedProblem(ProblemValidation, status, detail)
+	if "" != field {
+		problem.Errors = []FieldError{{field, detail}}
+	}
+	return problem
+}
+
+// faultProblem maps an error to the problem a client gets for it. Messages of database errors are never sent;
+// they are logged instead.
+func faultProblem(fault error) Problem {
+	var requestFault requestError
+	var validationError ValidationError
+	var transitionError TransitionError
+	problem := makeProblem(http.StatusInternalServerError, "The request could not be completed.")
+	switch {
+	case errors.As(fault, &requestFault):
+		problem = makeProblem(requestFault.status, requestFault.message)
+		if "" != requestFault.field &&
+			(http.StatusBadRequest == requestFault.status || http.StatusUnprocessableEntity == requestFault.status) {
+			problem = makeValidationProblem(requestFault.status, requestFault.field, requestFault.message)
+		}
+	case errors.As(fault, &validationError):
+		problem = makeValidationProblem(http.StatusBadRequest, validationError.Field, validationError.Message)
+	case errors.Is(fault, ErrNotFound):
+		problem = makeTypedProblem(ProblemNotFound, http.StatusNotFound, ErrNotFound.Error())
+	case errors.Is(fault, ErrDeletedEmail):
+		problem = makeTypedProblem(ProblemDeletedEmail, http.StatusConflict, fault.Error())
+	case errors.Is(fault, ErrDuplicateEmail):
+		problem = makeTypedProblem(ProblemDuplicateEmail, http.StatusConflict, ErrDuplicateEmail.Error())
+	case errors.As(fault, &transitionError):
+		problem = makeTypedProblem(ProblemConflict, http.StatusConflict, transitionError.Error())
+	case errors.Is(fault, ErrPreconditionFailed):
+		problem = makeTypedProblem(ProblemPrecondition, http.StatusPreconditionFailed, ErrPreconditionFailed.Error())
+	case errors.Is(fault, ErrConflict):
+		problem = makeTypedProblem(ProblemConflict, http.StatusConflict, ErrConflict.Error())
+	case errors.Is(fault, context.DeadlineExceeded):
+		problem = makeTypedProblem(ProblemTimeout, http.StatusGatewayTimeout, "The database did not respond in time.")
+	case errors.Is(fault, context.Canceled):
+		problem = makeProblem(http.StatusServiceUnavailable, "The request was canceled.")
+	case errors.Is(fault, ErrUnavailable):
+		problem = makeTypedProblem(ProblemUnavailable, http.StatusServiceUnavailable, ErrUnavailable.Error())
+	}
+	if problem.Detail != fault.Error() {
+		log.Printf("Answered %d %q for: %v", problem.Status, problem.Detail, fault)
+	}
+	return problem
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestFaultProblem(t *testing.T) {
+	rawError := "Error 1054: Unknown column 'password' in 'field list'"
+	for _, testCase := range []struct {
+		fault          error
+		expectedType   string
+		expectedStatus int
+		expectedDetail string
+		expectedErrors []FieldError
+	}{
+		{translateFault(sql.ErrNoRows), ProblemNotFound, http.StatusNotFound, ErrNotFound.Error(), nil},
+		{translateFault(&mysql.MySQLError{Number: 1062, Message: rawError}), ProblemDuplicateEmail, http.StatusConflict,
+			ErrDuplicateEmail.Error(), nil},
+		{deletedEmailError{"/subscribers/2/restore", translateFault(&mysql.MySQLError{Number: 1062, Message: rawError})},
+			ProblemDeletedEmail, http.StatusConflict, ErrDeletedEmail.Error() +
+				" Please restore it with POST /subscribers/2/restore, or use the address again once it is purged.", nil},
+		{ValidationError{"", "No subscriber fields to update."}, ProblemValidation, http.StatusBadRequest,
+			"No subscriber fields to update.", nil},
+		{ValidationError{"sort", "Please sort by index."}, ProblemValidation, http.StatusBadRequest, "Please sort by index.",
+			[]FieldError{{"sort", "Please sort by index."}}},
+		{requestError{http.StatusUnprocessableEntity, "index", "Subscriber index cannot be changed."}, ProblemValidation,
+			http.StatusUnprocessableEntity, "Subscriber index cannot be changed.",
+			[]FieldError{{"index", "Subscriber index cannot be changed."}}},
+		{requestError{http.StatusUnsupportedMediaType, "", "Please send JSON."}, "about:blank",
+			http.StatusUnsupportedMediaType, "Please send JSON.", nil},
+		{TransitionError{SubscriberDeactivated, SubscriberBounced}, ProblemConflict, http.StatusConflict,
+			TransitionError{SubscriberDeactivated, SubscriberBounced}.Error(), nil},
+		{ErrConflict, ProblemConflict, http.StatusConflict, ErrConflict.Error(), nil},
+		{translateFault(driver.ErrBadConn), ProblemUnavailable, http.StatusServiceUnavailable, ErrUnavailable.Error(), nil},
+		{fmt.Errorf("%w: %v", context.DeadlineExceeded, rawError), ProblemTimeout, http.StatusGatewayTimeout,
+			"The database did not respond in time.", nil},
+		{context.Canceled, "about:blank", http.StatusServiceUnavailable, "The request was canceled.", nil},
+		{&mysql.MySQLError{Number: 1054, Message: rawError}, "about:blank", http.StatusInternalServerError,
+			"The request could not be completed.", nil},
+	} {
+		problem := faultProblem(testCase.fault)
+		if testCase.expectedType != problem.Type || testCase.expectedStatus != problem.Status ||
+			testCase.expectedDetail != problem.Detail || !reflect.DeepEqual(testCase.expectedErrors, problem.Errors) ||
+			"" == problem.Title {
+			t.Errorf("ERROR %v was answered with %v", testCase.fault, problem)
+		}
+	}
+}
+
+func TestControllerSendsProblems(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	response := sendBody(fixture.dut.update, "PUT", "/subscribers/1?email_address=", "1", "", "")
+	expected := Problem{Type: ProblemValidation, Title: problemTitles[ProblemValidation], Status: http.StatusBadRequest,
+		Detail: "Subscriber email_address cannot be cleared.", Instance: "/subscribers/1",
+		Errors: []FieldError{{"email_address", "Subscriber email_address cannot be cleared."}}}
+	var problem Problem
+	if jsonError := json.Unmarshal(response.Body.Bytes(), &problem); jsonError != nil || !reflect.DeepEqual(expected, problem) {
+		t.Errorf("handler returned unexpected problem: got %s want %v", response.Body.String(), expected)
+	}
+	if contentType := response.Header().Get("Content-Type"); problemMediaType != contentType {
+		t.Errorf("handler returned wrong content type: got %v want %v", contentType, problemMediaType)
+	}
+	if status := response.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+
+	response = sendBody(fixture.dut.retrieve, "GET", "/subscribers/200", "200", "", "")
+	expected = Problem{Type: ProblemNotFound, Title: problemTitles[ProblemNotFound], Status: http.StatusNotFound,
+		Detail: ErrNotFound.Error(), Instance: "/subscribers/200"}
+	problem = Problem{}
+	if jsonError := json.Unmarshal(response.Body.Bytes(), &problem); jsonError != nil || !reflect.DeepEqual(expected, problem) {
+		t.Errorf("handler returned unexpected problem: got %s want %v", response.Body.String(), expected)
+	}
+
+	response = sendBody(fixture.dut.create, "POST", "/subscribers", "", "text/plain", "rey@starwars.com")
+	problem = Problem{}
+	if jsonError := json.Unmarshal(response.Body.Bytes(), &problem); jsonError != nil || "about:blank" != problem.Type ||
+		http.StatusText(http.StatusUnsupportedMediaType) != problem.Title || http.StatusUnsupportedMediaType != problem.Status {
+		t.Errorf("handler returned unexpected problem: got %s", response.Body.String())
+	}
+
+	for _, route := range []struct {
+		method         string
+		target         string
+		expectedStatus int
+		expectedAllow  string
+	}{
+		{"GET", "/nothing", http.StatusNotFound, ""},
+		{"GET", "/notsubscribers/1/", http.StatusNotFound, ""},
+		{"PATCH", "/subscribers", http.StatusMethodNotAllowed, "GET, POST"},
+		{"POST", "/subscribers/1", http.StatusMethodNotAllowed, "DELETE, GET, PATCH, PUT"},
+		{"DELETE", "/subscribers/1/status", http.StatusMethodNotAllowed, "GET, PUT"},
+	} {
+		response := httptest.NewRecorder()
+		fixture.dut.Handler().ServeHTTP(response, httptest.NewRequest(route.method, route.target, nil))
+		problem = Problem{}
+		if jsonError := json.Unmarshal(response.Body.Bytes(), &problem); jsonError != nil ||
+			route.expectedStatus != response.Code || route.expectedStatus != problem.Status ||
+			problemMediaType != response.Header().Get("Content-Type") || route.target != problem.Instance {
+			t.Errorf("handler returned unexpected problem for %s %s: got %v %s", route.method, route.target,
+				response.Code, response.Body.String())
+		}
+		if allow := response.Header().Get("Allow"); route.expectedAllow != allow {
+			t.Errorf("handler returned wrong Allow header for %s %s: got %v want %v", route.method, route.target, allow,
+				route.expectedAllow)
+		}
+	}
+	fixture.tearDown()
+}
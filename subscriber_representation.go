@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"gopkg.in/yaml.v3"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	jsonMediaType = "application/json"
+	csvMediaType  = "text/csv"
+	xmlMediaType  = "application/xml"
+	yamlMediaType = "application/yaml"
+)
+
+// Subscribers and pages of subscribers can be represented in every media type. Everything else is JSON only.
+var subscriberMediaTypes = []string{jsonMediaType, csvMediaType, xmlMediaType, yamlMediaType}
+
+var contentTypes = map[string]string{
+	jsonMediaType: jsonMediaType,
+	csvMediaType:  csvMediaType + "; charset=utf-8; header=present",
+	xmlMediaType:  xmlMediaType + "; charset=utf-8",
+	yamlMediaType: yamlMediaType + "; charset=utf-8",
+}
+
+var subscriberCSVHeader = []string{"index", "id", "email_address", "first_name", "last_name", "activation_flag", "created_at",
+	"updated_at", "deleted_at"}
+
+// negotiate picks the offered media type that the Accept header prefers. The most specific media range decides the
+// quality of an offer, and offers of the same quality are picked in the order given. A missing Accept header accepts
+// the first offer.
+func negotiate(accept string, offers []string) (string, error) {
+	if "" == strings.TrimSpace(accept) {
+		return offers[0], nil
+	}
+	chosen, chosenQuality := "", 0.0
+	for _, offer := range offers {
+		quality, specificity := 0.0, -1
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, parameters, parseError := mime.ParseMediaType(mediaRange)
+			if parseError != nil {
+				continue
+			}
+			rangeSpecificity := matchSpecificity(mediaType, offer)
+			if rangeSpecificity <= specificity {
+				continue
+			}
+			rangeQuality := 1.0
+			if q, found := parameters["q"]; found {
+				parsedQuality, qualityError := strconv.ParseFloat(q, 64)
+				if qualityError != nil || parsedQuality < 0 || parsedQuality > 1 {
+					continue
+				}
+				rangeQuality = parsedQuality
+			}
+			quality, specificity = rangeQuality, rangeSpecificity
+		}
+		if quality > chosenQuality {
+			chosen, chosenQuality = offer, quality
+		}
+	}
+	if "" == chosen {
+		return "", requestError{http.StatusNotAcceptable, "",
+			"Please accept one of " + strings.Join(offers, ", ") + "."}
+	}
+	return chosen, nil
+}
+
+// matchSpecificity tells how specifically a media range matches a media type: 2 for the type itself,
+// 1 for type/*, 0 for */* and -1 for no match.
+func matchSpecificity(mediaRange string, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	case "*/*" == mediaRange:
+		return 0
+	}
+	return -1
+}
+
+// encode represents a value in a media type. Only subscribers and pages have a CSV and an XML representation.
+func encode(mediaType string, value interface{}) ([]byte, error) {
+	switch mediaType {
+	case csvMediaType:
+		return encodeCSV(value)
+	case xmlMediaType:
+		return encodeXML(value)
+	case yamlMediaType:
+		return yaml.Marshal(value)
+	}
+	return json.Marshal(value)
+}
+
+func encodeCSV(value interface{}) ([]byte, error) {
+	var subscribers []Subscriber
+	switch typedValue := value.(type) {
+	case Page:
+		subscribers = typedValue.Subscribers
+	case *Subscriber:
+		subscribers = []Subscriber{*typedValue}
+	case Subscriber:
+		subscribers = []Subscriber{typedValue}
+	default:
+		return nil, errors.New("Only subscribers can be represented as CSV.")
+	}
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	records := [][]string{subscriberCSVHeader}
+	for _, subscriber := range subscribers {
+		index := ""
+		if 0 != subscriber.Index {
+			index = strconv.FormatUint(subscriber.Index, 10)
+		}
+		records = append(records, []string{index, subscriber.PublicID, subscriber.EmailAddress,
+			subscriber.FirstName, subscriber.LastName, strconv.FormatBool(subscriber.ActivationFlag),
+			csvTime(subscriber.CreatedAt), csvTime(subscriber.UpdatedAt), csvTime(subscriber.DeletedAt)})
+	}
+	if writeError := writer.WriteAll(records); writeError != nil {
+		return nil, writeError
+	}
+	return buffer.Bytes(), nil
+}
+
+func csvTime(at *time.Time) string {
+	if nil == at {
+		return ""
+	}
+	return at.Format(time.RFC3339Nano)
+}
+
+func encodeXML(value interface{}) ([]byte, error) {
+	var element string
+	switch value.(type) {
+	case Page:
+		element = "page"
+	case *Subscriber, Subscriber:
+		element = "subscriber"
+	default:
+		return nil, errors.New("Only subscribers can be represented as XML.")
+	}
+	buffer := bytes.NewBufferString(xml.Header)
+	if encodeError := xml.NewEncoder(buffer).EncodeElement(value, xml.StartElement{Name: xml.Name{Local: element}}); encodeError != nil {
+		return nil, encodeError
+	}
+	return buffer.Bytes(), nil
+}
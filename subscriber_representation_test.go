@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"encoding/xml"
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sendAccepting(handler http.HandlerFunc, method string, target string, index string,
+	accept string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, nil)
+	if "" != accept {
+		request.Header.Set("Accept", accept)
+	}
+	if "" != index {
+		request = mux.SetURLVars(request, map[string]string{"index": index})
+	}
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	return response
+}
+
+func TestNegotiate(t *testing.T) {
+	for _, testCase := range []struct {
+		accept   string
+		expected string
+	}{
+		{"", jsonMediaType},
+		{"*/*", jsonMediaType},
+		{"text/csv", csvMediaType},
+		{"text/*", csvMediaType},
+		{"application/xml, application/json", jsonMediaType},
+		{"application/xml, application/json;q=0.9", xmlMediaType},
+		{"application/*;q=0.5, application/yaml", yamlMediaType},
+		{"application/json;q=0, */*;q=0.1", csvMediaType},
+		{"application/json;q=abc, text/csv;q=0.2", csvMediaType},
+		{"application/pdf", ""},
+		{"application/json;q=0", ""},
+	} {
+		mediaType, negotiateError := negotiate(testCase.accept, subscriberMediaTypes)
+		if testCase.expected != mediaType || ("" == testCase.expected) != (negotiateError != nil) {
+			t.Errorf("ERROR negotiating %q chose %q, expected %q. %v", testCase.accept, mediaType, testCase.expected,
+				negotiateError)
+		}
+	}
+}
+
+func TestControllerNegotiatesRepresentations(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	response := sendAccepting(fixture.dut.list, "GET", "/subscribers?limit=2", "", "text/csv")
+	first, _ := fixture.model.Retrieve(context.Background(), 1)
+	second, _ := fixture.model.Retrieve(context.Background(), 2)
+	expectedCSV := "index,id,email_address,first_name,last_name,activation_flag,created_at,updated_at,deleted_at\n" +
+		"1," + first.PublicID + ",marcanthonyconcepcion@gmail.com,Marc Anthony,Concepcion,false," +
+		first.CreatedAt.Format(time.RFC3339Nano) + "," + first.UpdatedAt.Format(time.RFC3339Nano) + ",\n" +
+		"2," + second.PublicID + ",marcanthonyconcepcion@email.com,Marc,Concepcion,false," +
+		second.CreatedAt.Format(time.RFC3339Nano) + "," + second.UpdatedAt.Format(time.RFC3339Nano) + ",\n"
+	if expectedCSV != response.Body.String() || contentTypes[csvMediaType] != response.Header().Get("Content-Type") ||
+		"3" != response.Header().Get("X-Total-Count") || "" == response.Header().Get("Link") {
+		t.Errorf("handler returned unexpected CSV: got %v %s want %s", response.Header(), response.Body.String(), expectedCSV)
+	}
+	fixture.model.Delete(context.Background(), 2)
+	deleted, _ := fixture.model.List(context.Background(), ListQuery{Deleted: true})
+	response = sendAccepting(fixture.dut.list, "GET", "/subscribers?deleted=true", "", "text/csv")
+	if 1 != len(deleted) || !strings.HasSuffix(response.Body.String(),
+		","+deleted[0].UpdatedAt.Format(time.RFC3339Nano)+","+deleted[0].DeletedAt.Format(time.RFC3339Nano)+"\n") {
+		t.Errorf("handler returned unexpected CSV of the trash: %s", response.Body.String())
+	}
+	fixture.model.Restore(context.Background(), 2)
+
+	response = sendAccepting(fixture.dut.retrieve, "GET", "/subscribers/2", "2", "application/xml")
+	var subscriber Subscriber
+	if xmlError := xml.Unmarshal(response.Body.Bytes(), &subscriber); xmlError != nil ||
+		fixture.expectedRecords[1] != unstamped(subscriber) || !strings.Contains(response.Body.String(), "<subscriber>") ||
+		contentTypes[xmlMediaType] != response.Header().Get("Content-Type") {
+		t.Errorf("handler returned unexpected XML: %s", response.Body.String())
+	}
+
+	response = sendAccepting(fixture.dut.list, "GET", "/subscribers", "", "application/xml")
+	var page Page
+	if xmlError := xml.Unmarshal(response.Body.Bytes(), &page); xmlError != nil || 3 != page.Total ||
+		3 != len(page.Subscribers) || fixture.expectedRecords[2] != unstamped(page.Subscribers[2]) {
+		t.Errorf("handler returned unexpected XML page: %s", response.Body.String())
+	}
+
+	response = sendAccepting(fixture.dut.list, "GET", "/subscribers", "", "application/yaml")
+	page = Page{}
+	if yamlError := yaml.Unmarshal(response.Body.Bytes(), &page); yamlError != nil || 3 != page.Total ||
+		fixture.expectedRecords[0] != unstamped(page.Subscribers[0]) || contentTypes[yamlMediaType] != response.Header().Get("Content-Type") {
+		t.Errorf("handler returned unexpected YAML page: %s", response.Body.String())
+	}
+
+	response = sendAccepting(fixture.dut.retrieve, "GET", "/subscribers/1", "1", "")
+	if jsonMediaType != response.Header().Get("Content-Type") || "Accept" != response.Header().Get("Vary") {
+		t.Errorf("handler returned wrong headers for JSON: %v", response.Header())
+	}
+	response = sendAccepting(fixture.dut.activate, "PATCH", "/subscribers/1?activation_flag=true", "1", "")
+	if jsonMediaType != response.Header().Get("Content-Type") {
+		t.Errorf("handler returned wrong content type: got %v want %v", response.Header().Get("Content-Type"), jsonMediaType)
+	}
+
+	for _, testCase := range []struct {
+		handler http.HandlerFunc
+		method  string
+		target  string
+		index   string
+		accept  string
+	}{
+		{fixture.dut.list, "GET", "/subscribers", "", "application/pdf"},
+		{fixture.dut.retrieve, "GET", "/subscribers/1", "1", "text/html"},
+		{fixture.dut.accepting(fixture.dut.retrieve, subscriberMediaTypes...), "GET", "/subscribers/1", "1", "image/*"},
+		{fixture.dut.accepting(fixture.dut.create), "POST", "/subscribers?email_address=rey%40starwars.com", "", "text/csv"},
+	} {
+		response = sendAccepting(testCase.handler, testCase.method, testCase.target, testCase.index, testCase.accept)
+		if status := response.Code; status != http.StatusNotAcceptable ||
+			problemMediaType != response.Header().Get("Content-Type") {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v", testCase.accept, status,
+				http.StatusNotAcceptable)
+		}
+	}
+	if subscribers, _ := fixture.model.List(context.Background(), ListQuery{}); 3 != len(subscribers) {
+		t.Errorf("ERROR a request that is not acceptable created a subscriber: %v", subscribers)
+	}
+	fixture.tearDown()
+}
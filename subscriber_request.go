@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const bodyLimit = 1 << 20
+
+// requestError rejects a request with its status code. Field names the subscriber field at fault, if any.
+type requestError struct {
+	status  int
+	field   string
+	message string
+}
+
+func (fault requestError) Error() string {
+	return fault.message
+}
+
+// subscriberFields reads the subscriber fields of a request. A request with a body sends them as a JSON object
+// (application/json) or as a form (application/x-www-form-urlencoded), where only the given fields are accepted.
+// A request without a body keeps sending them in the query string.
+func subscriberFields(response http.ResponseWriter, request *http.Request, fields ...string) (map[string]string, error) {
+	values := make(map[string]string)
+	if 0 == request.ContentLength && "" == request.Header.Get("Content-Type") {
+		for field, fieldValues := range request.URL.Query() {
+			values[field] = fieldValues[0]
+		}
+		return values, nil
+	}
+	for _, field := range fields {
+		if _, found := request.URL.Query()[field]; found {
+			return nil, requestError{http.StatusBadRequest, "",
+				"Please send the subscriber fields either in the request body or in the query string, not both."}
+		}
+	}
+	mediaType, _, mediaTypeError := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if mediaTypeError != nil {
+		mediaType = ""
+	}
+	request.Body = http.MaxBytesReader(response, request.Body, bodyLimit)
+	switch mediaType {
+	case "application/json":
+		var object map[string]interface{}
+		decoder := json.NewDecoder(request.Body)
+		if decodeError := decoder.Decode(&object); decodeError != nil {
+			return nil, bodyError(decodeError)
+		}
+		if decoder.More() {
+			return nil, requestError{http.StatusBadRequest, "", "Invalid request body. Unexpected data after the JSON object."}
+		}
+		for field, value := range object {
+			if !acceptedField(field, fields) {
+				return nil, unknownFieldError(field, fields)
+			}
+			switch typedValue := value.(type) {
+			case string:
+				values[field] = typedValue
+			case bool:
+				if "activation_flag" != field {
+					return nil, requestError{http.StatusBadRequest, field, "Subscriber " + field + " must be a string."}
+				}
+				values[field] = strconv.FormatBool(typedValue)
+			default:
+				return nil, requestError{http.StatusBadRequest, field, "Subscriber " + field + " must be a string."}
+			}
+		}
+	case "application/x-www-form-urlencoded":
+		if parseError := request.ParseForm(); parseError != nil {
+			return nil, bodyError(parseError)
+		}
+		for field, fieldValues := range request.PostForm {
+			if !acceptedField(field, fields) {
+				return nil, unknownFieldError(field, fields)
+			}
+			if 1 != len(fieldValues) {
+				return nil, requestError{http.StatusBadRequest, field, "Subscriber " + field + " can only be sent once."}
+			}
+			values[field] = fieldValues[0]
+		}
+	default:
+		return nil, requestError{http.StatusUnsupportedMediaType, "",
+			"Please send the subscriber as JSON (application/json) or as a form (application/x-www-form-urlencoded)."}
+	}
+	return values, nil
+}
+
+func bodyError(fault error) error {
+	if strings.Contains(fault.Error(), "request body too large") {
+		return requestError{http.StatusRequestEntityTooLarge, "",
+			"The request body exceeds " + strconv.Itoa(bodyLimit) + " bytes."}
+	}
+	return requestError{http.StatusBadRequest, "", "Invalid request body. " + fault.Error()}
+}
+
+func acceptedField(field string, fields []string) bool {
+	for _, accepted := range fields {
+		if accepted == field {
+			return true
+		}
+	}
+	return false
+}
+
+func unknownFieldError(field string, fields []string) error {
+	return requestError{http.StatusBadRequest, field,
+		"Unknown subscriber field " + field + ". Accepted fields are " + strings.Join(fields, ", ") + "."}
+}
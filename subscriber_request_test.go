@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func sendBody(handler http.HandlerFunc, method string, target string, index string, contentType string,
+	body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	if "" != contentType {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if "" != index {
+		request = mux.SetURLVars(request, map[string]string{"index": index})
+	}
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	return response
+}
+
+func TestControllerAcceptsBodies(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	response := sendBody(fixture.dut.create, "POST", "/subscribers", "", "application/json; charset=utf-8",
+		`{"email_address": "rey@starwars.com", "first_name": "Rey", "activation_flag": true}`)
+	if status := response.Code; status != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v. %s", status, http.StatusCreated, response.Body.String())
+	}
+	response = sendBody(fixture.dut.create, "POST", "/subscribers", "", "application/x-www-form-urlencoded",
+		"email_address=finn%40starwars.com&last_name=FN-2187")
+	if status := response.Code; status != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v. %s", status, http.StatusCreated, response.Body.String())
+	}
+	response = sendBody(fixture.dut.update, "PUT", "/subscribers/4", "4", "application/json",
+		`{"last_name": "Skywalker"}`)
+	if status := response.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v. %s", status, http.StatusOK, response.Body.String())
+	}
+	response = sendBody(fixture.dut.update, "PUT", "/subscribers/5", "5", "application/x-www-form-urlencoded",
+		"last_name=")
+	if status := response.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v. %s", status, http.StatusOK, response.Body.String())
+	}
+	response = sendBody(fixture.dut.activate, "PATCH", "/subscribers/5", "5", "application/json",
+		`{"activation_flag": true}`)
+	if status := response.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v. %s", status, http.StatusOK, response.Body.String())
+	}
+
+	for _, expected := range []Subscriber{
+		{Index: 4, EmailAddress: "rey@starwars.com", FirstName: "Rey", LastName: "Skywalker", ActivationFlag: true},
+		{Index: 5, EmailAddress: "finn@starwars.com", ActivationFlag: true},
+	} {
+		subscriber, retrieveFail := fixture.model.Retrieve(context.Background(), expected.Index)
+		if retrieveFail != nil {
+			t.Fatalf("ERROR retrieving subscriber #%d. %s", expected.Index, retrieveFail.Error())
+		}
+		if expected != unstamped(*subscriber) {
+			t.Errorf("ERROR expected %v != Actual %v", expected, *subscriber)
+		}
+	}
+	fixture.tearDown()
+}
+
+func TestControllerRejectsBodies(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	for _, testCase := range []struct {
+		handler        http.HandlerFunc
+		target         string
+		contentType    string
+		body           string
+		expectedStatus int
+	}{
+		{fixture.dut.create, "/subscribers", "application/json", `{"email_address": "rey@starwars.com", "index": 9}`,
+			http.StatusBadRequest},
+		{fixture.dut.create, "/subscribers", "application/json", `{"email_address": 42}`, http.StatusBadRequest},
+		{fixture.dut.create, "/subscribers", "application/json", `{"email_address": "rey@starwars.com"} {}`,
+			http.StatusBadRequest},
+		{fixture.dut.create, "/subscribers", "application/json", `{"email_address": `, http.StatusBadRequest},
+		{fixture.dut.create, "/subscribers?email_address=han%40starwars.com", "application/json",
+			`{"email_address": "rey@starwars.com"}`, http.StatusBadRequest},
+		{fixture.dut.create, "/subscribers", "text/plain", "email_address=rey@starwars.com",
+			http.StatusUnsupportedMediaType},
+		{fixture.dut.create, "/subscribers", "application/json",
+			`{"email_address": "` + strings.Repeat("x", bodyLimit) + `"}`, http.StatusRequestEntityTooLarge},
+		{fixture.dut.update, "/subscribers/1", "application/x-www-form-urlencoded", "activation_flag=true",
+			http.StatusBadRequest},
+		{fixture.dut.update, "/subscribers/1", "application/x-www-form-urlencoded", "last_name=a&last_name=b",
+			http.StatusBadRequest},
+		{fixture.dut.activate, "/subscribers/1", "application/json", `{"activation_flag": "maybe"}`, http.StatusBadRequest},
+		{fixture.dut.activate, "/subscribers/1", "application/xml", `<activation_flag>true</activation_flag>`,
+			http.StatusUnsupportedMediaType},
+	} {
+		response := sendBody(testCase.handler, "POST", testCase.target, "1", testCase.contentType, testCase.body)
+		if status := response.Code; status != testCase.expectedStatus {
+			t.Errorf("handler returned wrong status code for %.60s: got %v want %v", testCase.body, status,
+				testCase.expectedStatus)
+		}
+	}
+	fetchedRecords, _ := fixture.model.List(context.Background(), ListQuery{})
+	if len(fixture.expectedRecords) != len(fetchedRecords) {
+		t.Errorf("ERROR rejected bodies changed the records: %v", fetchedRecords)
+	}
+	fixture.tearDown()
+}
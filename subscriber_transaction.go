@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"strconv"
+)
+
+func CreateActivatedSubscriber(ctx context.Context, store SubscriberStore, subscriber Subscriber) (uint64, error) {
+	var index uint64
+	transactionFail := store.Transaction(ctx, func(transaction SubscriberStore) error {
+		result, createFail := transaction.Create(ctx, subscriber)
+		if createFail != nil {
+			return createFail
+		}
+		lastInsertId, lastInsertIdFail := result.LastInsertId()
+		if lastInsertIdFail != nil {
+			return lastInsertIdFail
+		}
+		index = uint64(lastInsertId)
+		_, activateFail := transaction.Activate(ctx, index, true)
+		return activateFail
+	})
+	return index, transactionFail
+}
+
+// The unique email_address constraint is checked per statement, so the first subscriber is moved
+// to a placeholder address while the second one takes over its address.
+func SwapEmailAddresses(ctx context.Context, store SubscriberStore, first uint64, second uint64) error {
+	if first == second {
+		return ValidationError{"with", "A subscriber cannot swap email addresses with itself."}
+	}
+	return store.Transaction(ctx, func(transaction SubscriberStore) error {
+		firstSubscriber, retrieveFail := transaction.Retrieve(ctx, first)
+		if retrieveFail != nil {
+			return retrieveFail
+		}
+		secondSubscriber, retrieveFail := transaction.Retrieve(ctx, second)
+		if retrieveFail != nil {
+			return retrieveFail
+		}
+		placeholder := "swap-" + strconv.FormatUint(first, 10) + "-" + strconv.FormatUint(second, 10) + "@invalid"
+		for _, update := range []SubscriberUpdate{
+			{Index: first, EmailAddress: &placeholder},
+			{Index: second, EmailAddress: &firstSubscriber.EmailAddress},
+			{Index: first, EmailAddress: &secondSubscriber.EmailAddress},
+		} {
+			if _, updateFail := transaction.Update(ctx, update); updateFail != nil {
+				return updateFail
+			}
+		}
+		return nil
+	})
+}
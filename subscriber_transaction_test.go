@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestTransactionCommits(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		lastName := "Skywalker"
+		transactionFail := fixture.dut.Transaction(context.Background(), func(transaction SubscriberStore) error {
+			if _, updateFail := transaction.Update(context.Background(), SubscriberUpdate{Index: 1, LastName: &lastName}); updateFail != nil {
+				return updateFail
+			}
+			_, deleteFail := transaction.Delete(context.Background(), 2)
+			return deleteFail
+		})
+		if transactionFail != nil {
+			t.Fatalf("ERROR committing a transaction. %s", transactionFail.Error())
+		}
+		fetchedRecords, listFail := fixture.dut.List(context.Background(), ListQuery{})
+		if listFail != nil {
+			t.Fatalf("ERROR fetching database records. %s", listFail.Error())
+		}
+		expectedRecords := []Subscriber{fixture.expectedRecords[0], fixture.expectedRecords[2]}
+		expectedRecords[0].LastName = lastName
+		if len(expectedRecords) != len(fetchedRecords) {
+			t.Fatalf("ERROR expected %v != Actual %v", expectedRecords, fetchedRecords)
+		}
+		for index := range expectedRecords {
+			if expectedRecords[index] != unstamped(fetchedRecords[index]) {
+				t.Errorf("ERROR fetching database records. Expected %v != Actual %v", expectedRecords[index], fetchedRecords[index])
+			}
+		}
+	})
+}
+
+func TestTransactionRollsBack(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		workFail := errors.New("work failed")
+		transactionFail := fixture.dut.Transaction(context.Background(), func(transaction SubscriberStore) error {
+			if _, deleteFail := transaction.Delete(context.Background(), 1); deleteFail != nil {
+				return deleteFail
+			}
+			return workFail
+		})
+		if !errors.Is(transactionFail, workFail) {
+			t.Errorf("ERROR expected %v from a failed transaction, got %v", workFail, transactionFail)
+		}
+		func() {
+			defer func() {
+				if fault := recover(); fault == nil {
+					t.Errorf("ERROR the panic in a transaction was swallowed.")
+				}
+			}()
+			_ = fixture.dut.Transaction(context.Background(), func(transaction SubscriberStore) error {
+				if _, deleteFail := transaction.Delete(context.Background(), 2); deleteFail != nil {
+					return deleteFail
+				}
+				panic("work panicked")
+			})
+		}()
+		fetchedRecords, listFail := fixture.dut.List(context.Background(), ListQuery{})
+		if listFail != nil {
+			t.Fatalf("ERROR fetching database records. %s", listFail.Error())
+		}
+		if len(fixture.expectedRecords) != len(fetchedRecords) {
+			t.Fatalf("ERROR expected %v != Actual %v", fixture.expectedRecords, fetchedRecords)
+		}
+		for index := range fixture.expectedRecords {
+			if fixture.expectedRecords[index] != unstamped(fetchedRecords[index]) {
+				t.Errorf("ERROR fetching database records. Expected %v != Actual %v",
+					fixture.expectedRecords[index], fetchedRecords[index])
+			}
+		}
+	})
+}
+
+func TestCreateActivatedSubscriber(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		index, createFail := CreateActivatedSubscriber(context.Background(), fixture.dut,
+			Subscriber{EmailAddress: "riseofskywalker@starwars.com", FirstName: "Rey", LastName: "Palpatine"})
+		if createFail != nil {
+			t.Fatalf("ERROR creating an activated subscriber. %s", createFail.Error())
+		}
+		subscriber, retrieveFail := fixture.dut.Retrieve(context.Background(), index)
+		if retrieveFail != nil {
+			t.Fatalf("ERROR retrieving database record at index %d. %s", index, retrieveFail.Error())
+		}
+		if !subscriber.ActivationFlag || "riseofskywalker@starwars.com" != subscriber.EmailAddress {
+			t.Errorf("ERROR subscriber %v was not created activated.", *subscriber)
+		}
+		if _, duplicateFail := CreateActivatedSubscriber(context.Background(), fixture.dut,
+			Subscriber{EmailAddress: "riseofskywalker@starwars.com"}); duplicateFail == nil {
+			t.Errorf("ERROR duplicate email address was accepted.")
+		}
+	})
+}
+
+func TestSwapEmailAddresses(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		if swapFail := SwapEmailAddresses(context.Background(), fixture.dut, 1, 3); swapFail != nil {
+			t.Fatalf("ERROR swapping email addresses. %s", swapFail.Error())
+		}
+		for _, swap := range []struct {
+			index        uint64
+			emailAddress string
+		}{
+			{1, fixture.expectedRecords[2].EmailAddress},
+			{3, fixture.expectedRecords[0].EmailAddress},
+		} {
+			subscriber, retrieveFail := fixture.dut.Retrieve(context.Background(), swap.index)
+			if retrieveFail != nil {
+				t.Fatalf("ERROR retrieving database record at index %d. %s", swap.index, retrieveFail.Error())
+			}
+			if swap.emailAddress != subscriber.EmailAddress {
+				t.Errorf("ERROR subscriber #%d has email address %s, expected %s.", swap.index,
+					subscriber.EmailAddress, swap.emailAddress)
+			}
+		}
+		if swapFail := SwapEmailAddresses(context.Background(), fixture.dut, 1, 200); !errors.Is(swapFail, sql.ErrNoRows) {
+			t.Errorf("ERROR expected %v when swapping with a missing subscriber, got %v", sql.ErrNoRows, swapFail)
+		}
+		subscriber, _ := fixture.dut.Retrieve(context.Background(), 1)
+		if fixture.expectedRecords[2].EmailAddress != subscriber.EmailAddress {
+			t.Errorf("ERROR failed swap changed the email address to %s.", subscriber.EmailAddress)
+		}
+	})
+}
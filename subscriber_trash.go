@@ -0,0 +1,36 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// defaultPurgeRetention is how long deleted subscribers are kept when the configuration sets no retention.
+const defaultPurgeRetention = 30 * 24 * time.Hour
+
+// Restore takes a deleted subscriber out of the trash. A subscriber that is missing or not deleted affects no rows.
+// Deleted subscribers keep their email address, so a restored subscriber never collides with another one. Taking the
+// address of a deleted subscriber fails with ErrDeletedEmail at the controller, which points to the restore instead.
+func (records Records) Restore(ctx context.Context, index uint64) (sql.Result, error) {
+	ctx, cancel := records.withTimeout(ctx)
+	defer cancel()
+	result, restoreFail := records.exec(ctx, "update `subscribers` set `deleted_at`=null, `updated_at`=?, "+
+		"`version`=`version`+1 where `index`=? and `deleted_at` is not null", storeTime(), index)
+	return result, restoreFail
+}
+
+// Purge removes the subscribers that were deleted before deletedBefore for good.
+func (records Records) Purge(ctx context.Context, deletedBefore time.Time) (sql.Result, error) {
+	ctx, cancel := records.withTimeout(ctx)
+	defer cancel()
+	result, purgeFail := records.exec(ctx, "delete from `subscribers` where `deleted_at` is not null and `deleted_at` < ?",
+		deletedBefore.UTC())
+	return result, purgeFail
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubscriberTrash(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		ctx := context.Background()
+		expectRows := func(expected int64, result interface{ RowsAffected() (int64, error) }, fault error) {
+			t.Helper()
+			if fault != nil {
+				t.Fatalf("ERROR changing the trash. %s", fault.Error())
+			}
+			if rowsAffected, _ := result.RowsAffected(); expected != rowsAffected {
+				t.Errorf("ERROR expected %d affected rows, got %d", expected, rowsAffected)
+			}
+		}
+		result, deleteFail := fixture.dut.Delete(ctx, 2)
+		expectRows(1, result, deleteFail)
+		result, deleteFail = fixture.dut.Delete(ctx, 2)
+		expectRows(0, result, deleteFail)
+		if _, retrieveFail := fixture.dut.Retrieve(ctx, 2); !errors.Is(retrieveFail, ErrNotFound) {
+			t.Errorf("ERROR expected %v when retrieving a deleted subscriber, got %v", ErrNotFound, retrieveFail)
+		}
+		if _, versionFail := fixture.dut.Version(ctx, 2); !errors.Is(versionFail, ErrNotFound) {
+			t.Errorf("ERROR expected %v for the version of a deleted subscriber, got %v", ErrNotFound, versionFail)
+		}
+		firstName := "Ghost"
+		result, updateFail := fixture.dut.Update(ctx, SubscriberUpdate{Index: 2, FirstName: &firstName})
+		expectRows(0, result, updateFail)
+		result, activateFail := fixture.dut.Activate(ctx, 2, true)
+		expectRows(0, result, activateFail)
+		if _, createFail := fixture.dut.Create(ctx, fixture.expectedRecords[1]); !errors.Is(createFail, ErrDuplicateEmail) {
+			t.Errorf("ERROR expected %v when taking the email address of a deleted subscriber, got %v", ErrDuplicateEmail,
+				createFail)
+		}
+
+		if live, _ := fixture.dut.List(ctx, ListQuery{}); 2 != len(live) || 1 != live[0].Index || 3 != live[1].Index {
+			t.Errorf("ERROR listing left in deleted subscribers: %v", live)
+		}
+		deleted, listFail := fixture.dut.List(ctx, ListQuery{Deleted: true})
+		if listFail != nil || 1 != len(deleted) || nil == deleted[0].DeletedAt {
+			t.Fatalf("ERROR listing the trash returned %v. %v", deleted, listFail)
+		}
+		trashed := unstamped(deleted[0])
+		trashed.DeletedAt = nil
+		if fixture.expectedRecords[1] != trashed {
+			t.Errorf("ERROR expected %v in the trash, got %v", fixture.expectedRecords[1], deleted[0])
+		}
+		if count, countFail := fixture.dut.Count(ctx, ListQuery{Deleted: true}); countFail != nil || 1 != count {
+			t.Errorf("ERROR counting the trash returned %d. %v", count, countFail)
+		}
+		for _, emailAddress := range []string{fixture.expectedRecords[1].EmailAddress, fixture.expectedRecords[0].EmailAddress} {
+			owners, listFail := fixture.dut.List(ctx, ListQuery{Deleted: true, EmailAddress: emailAddress})
+			if listFail != nil || (emailAddress == fixture.expectedRecords[1].EmailAddress) != (1 == len(owners)) {
+				t.Errorf("ERROR listing the trash by %s returned %v. %v", emailAddress, owners, listFail)
+			}
+		}
+
+		result, restoreFail := fixture.dut.Restore(ctx, 2)
+		expectRows(1, result, restoreFail)
+		result, restoreFail = fixture.dut.Restore(ctx, 2)
+		expectRows(0, result, restoreFail)
+		if restored, retrieveFail := fixture.dut.Retrieve(ctx, 2); retrieveFail != nil ||
+			fixture.expectedRecords[1] != unstamped(*restored) || nil != restored.DeletedAt {
+			t.Errorf("ERROR expected the restored subscriber %v, got %v. %v", fixture.expectedRecords[1], restored, retrieveFail)
+		}
+		if version, _ := fixture.dut.Version(ctx, 2); 3 != version {
+			t.Errorf("ERROR expected version 3 after a delete and a restore, got %d", version)
+		}
+
+		for _, index := range []uint64{2, 3} {
+			result, deleteFail = fixture.dut.Delete(ctx, index)
+			expectRows(1, result, deleteFail)
+		}
+		result, purgeFail := fixture.dut.Purge(ctx, storeTime().Add(-time.Hour))
+		expectRows(0, result, purgeFail)
+		result, purgeFail = fixture.dut.Purge(ctx, storeTime().Add(time.Second))
+		expectRows(2, result, purgeFail)
+		if deleted, _ = fixture.dut.List(ctx, ListQuery{Deleted: true}); 0 != len(deleted) {
+			t.Errorf("ERROR purging left %v in the trash", deleted)
+		}
+		result, restoreFail = fixture.dut.Restore(ctx, 3)
+		expectRows(0, result, restoreFail)
+		if _, createFail := fixture.dut.Create(ctx, fixture.expectedRecords[1]); createFail != nil {
+			t.Errorf("ERROR the email address of a purged subscriber is still taken. %s", createFail.Error())
+		}
+	})
+}
+
+func TestControllerTrash(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	if response := sendBody(fixture.dut.delete, "DELETE", "/subscribers/2", "2", "", ""); http.StatusNoContent != response.Code {
+		t.Errorf("handler returned wrong status code: got %v want %v", response.Code, http.StatusNoContent)
+	}
+	if response := sendBody(fixture.dut.retrieve, "GET", "/subscribers/2", "2", "", ""); http.StatusNotFound != response.Code {
+		t.Errorf("handler returned wrong status code: got %v want %v", response.Code, http.StatusNotFound)
+	}
+	response, page := listPage(t, fixture.dut, "/subscribers?deleted=true")
+	if 1 != page.Total || 2 != page.Subscribers[0].Index || nil == page.Subscribers[0].DeletedAt {
+		t.Errorf("handler returned unexpected trash: %s", response.Body.String())
+	}
+	if response, _ = listPage(t, fixture.dut, "/subscribers?deleted=maybe"); http.StatusBadRequest != response.Code {
+		t.Errorf("handler returned wrong status code: got %v want %v", response.Code, http.StatusBadRequest)
+	}
+
+	deletedEmailAddress := fixture.expectedRecords[1].EmailAddress
+	for _, response := range []*httptest.ResponseRecorder{
+		sendBody(fixture.dut.create, "POST", "/subscribers?email_address="+deletedEmailAddress, "", "", ""),
+		sendBody(fixture.dut.update, "PUT", "/subscribers/1?email_address="+deletedEmailAddress, "1", "", ""),
+	} {
+		var problem Problem
+		if jsonError := json.Unmarshal(response.Body.Bytes(), &problem); jsonError != nil ||
+			http.StatusConflict != response.Code || ProblemDeletedEmail != problem.Type ||
+			!strings.Contains(problem.Detail, "POST /subscribers/2/restore") {
+			t.Errorf("handler returned unexpected problem for the address of a deleted subscriber: %v %s", response.Code,
+				response.Body.String())
+		}
+	}
+
+	response = sendBody(fixture.dut.restore, "POST", "/subscribers/2/restore", "2", "", "")
+	expectedMessage := ConvertToJson(Update{"Record restored", fixture.expectedRecords[1]})
+	if status := response.Code; status != http.StatusOK || expectedMessage != unstampedJson(response.Body.String()) ||
+		`"3"` != response.Header().Get("ETag") {
+		t.Errorf("handler returned unexpected response: got %v %v %s want %s", status, response.Header(),
+			response.Body.String(), expectedMessage)
+	}
+	response = sendBody(fixture.dut.create, "POST", "/subscribers?email_address="+deletedEmailAddress, "", "", "")
+	if !strings.Contains(response.Body.String(), ProblemDuplicateEmail) {
+		t.Errorf("handler returned unexpected problem for the address of a restored subscriber: %s", response.Body.String())
+	}
+	for _, index := range []string{"2", "200"} {
+		response = sendBody(fixture.dut.restore, "POST", "/subscribers/"+index+"/restore", index, "", "")
+		if status := response.Code; status != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code for #%s: got %v want %v", index, status, http.StatusNotFound)
+		}
+	}
+
+	sendBody(fixture.dut.delete, "DELETE", "/subscribers/3", "3", "", "")
+	response = sendBody(fixture.dut.purge, "POST", "/subscribers/purge", "", "", "")
+	if status := response.Code; status != http.StatusOK || !strings.Contains(response.Body.String(), "Purged 0 subscribers") {
+		t.Errorf("handler purged a subscriber before its retention: got %v %s", status, response.Body.String())
+	}
+	controller := makeSubscriberController(fixture.model, MVCConfiguration{PageSize: 10, MaxPageSize: 20,
+		PurgeRetention: time.Nanosecond})
+	time.Sleep(time.Millisecond)
+	response = sendBody(controller.purge, "POST", "/subscribers/purge", "", "", "")
+	if status := response.Code; status != http.StatusOK || !strings.Contains(response.Body.String(), "Purged 1 subscribers") {
+		t.Errorf("handler did not purge the deleted subscriber: got %v %s", status, response.Body.String())
+	}
+	fixture.tearDown()
+}
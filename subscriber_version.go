@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IfVersion runs work in a transaction when matches accepts the version of the subscriber. Otherwise, and when the
+// subscriber does not exist, it fails with ErrPreconditionFailed. The subscriber stays locked while work runs.
+func IfVersion(ctx context.Context, store SubscriberStore, index uint64, matches func(version uint64) bool,
+	work func(store SubscriberStore) error) error {
+	return store.Transaction(ctx, func(transaction SubscriberStore) error {
+		version, versionFail := transaction.Version(ctx, index)
+		if errors.Is(versionFail, ErrNotFound) {
+			return ErrPreconditionFailed
+		}
+		if versionFail != nil {
+			return versionFail
+		}
+		if !matches(version) {
+			return ErrPreconditionFailed
+		}
+		return work(transaction)
+	})
+}
+
+// entityTag is the strong ETag of a representation of a subscriber version. Only JSON goes without a suffix.
+func entityTag(version uint64, mediaType string) string {
+	tag := strconv.FormatUint(version, 10)
+	if jsonMediaType != mediaType {
+		tag += "-" + mediaType[strings.LastIndexByte(mediaType, '/')+1:]
+	}
+	return `"` + tag + `"`
+}
+
+func entityTags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); "" != tag {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// versionMatcher accepts the versions of the ETags in an If-Match header, or any version for *.
+// Weak ETags never match, as If-Match compares strongly.
+func versionMatcher(ifMatch string) func(version uint64) bool {
+	tags := entityTags(ifMatch)
+	return func(version uint64) bool {
+		for _, tag := range tags {
+			if "*" == tag {
+				return true
+			}
+			if len(tag) < 2 || '"' != tag[0] || '"' != tag[len(tag)-1] {
+				continue
+			}
+			if strconv.FormatUint(version, 10) == strings.SplitN(tag[1:len(tag)-1], "-", 2)[0] {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// noneMatch tells whether an If-None-Match header holds the ETag, or *. It compares weakly.
+func noneMatch(ifNoneMatch string, tag string) bool {
+	for _, candidate := range entityTags(ifNoneMatch) {
+		if "*" == candidate || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModifiedSince tells whether a subscriber updated at updatedAt is unchanged since an If-Modified-Since date.
+// HTTP dates have whole seconds, so the update time is compared to the second. Invalid dates are ignored.
+func notModifiedSince(ifModifiedSince string, updatedAt *time.Time) bool {
+	if "" == ifModifiedSince || nil == updatedAt {
+		return false
+	}
+	since, parseError := http.ParseTime(ifModifiedSince)
+	if parseError != nil {
+		return false
+	}
+	return !updatedAt.Truncate(time.Second).After(since)
+}
+
+// lastModified formats an update time as the Last-Modified header does.
+func lastModified(updatedAt time.Time) string {
+	return updatedAt.UTC().Format(http.TimeFormat)
+}
@@ -0,0 +1,221 @@
+/*
+ * Copyright (c) 2021.
+ * Marc Concepcion
+ * marcanthonyconcepcion@gmail.com
+ */
+
+package MarcGoRESTAPIDemo
+
+import (
+	"context"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubscriberVersions(t *testing.T) {
+	runSubscriberModelTest(t, func(t *testing.T, fixture SubscriberModelTestFixture) {
+		ctx := context.Background()
+		expectVersion := func(expected uint64) {
+			t.Helper()
+			if version, versionFail := fixture.dut.Version(ctx, 1); versionFail != nil || expected != version {
+				t.Errorf("ERROR subscriber has version %d, expected %d. %v", version, expected, versionFail)
+			}
+		}
+		expectVersion(1)
+		firstName := "Luke"
+		if _, updateFail := fixture.dut.Update(ctx, SubscriberUpdate{Index: 1, FirstName: &firstName}); updateFail != nil {
+			t.Fatalf("ERROR updating a subscriber. %s", updateFail.Error())
+		}
+		expectVersion(2)
+		for count := 0; count < 2; count++ {
+			if _, activateFail := fixture.dut.Activate(ctx, 1, true); activateFail != nil {
+				t.Fatalf("ERROR activating a subscriber. %s", activateFail.Error())
+			}
+		}
+		expectVersion(3)
+
+		lastName := "Skywalker"
+		update := func(store SubscriberStore) error {
+			_, updateFail := store.Update(ctx, SubscriberUpdate{Index: 1, LastName: &lastName})
+			return updateFail
+		}
+		stale := func(version uint64) bool { return 2 == version }
+		if ifVersionFail := IfVersion(ctx, fixture.dut, 1, stale, update); !errors.Is(ifVersionFail, ErrPreconditionFailed) {
+			t.Errorf("ERROR expected %v for a stale version, got %v", ErrPreconditionFailed, ifVersionFail)
+		}
+		if subscriber, _ := fixture.dut.Retrieve(ctx, 1); lastName == subscriber.LastName {
+			t.Errorf("ERROR a stale version changed subscriber %v", *subscriber)
+		}
+		current := func(version uint64) bool { return 3 == version }
+		if ifVersionFail := IfVersion(ctx, fixture.dut, 1, current, update); ifVersionFail != nil {
+			t.Errorf("ERROR changing the current version. %s", ifVersionFail.Error())
+		}
+		expectVersion(4)
+		anyVersion := func(uint64) bool { return true }
+		if ifVersionFail := IfVersion(ctx, fixture.dut, 200, anyVersion, update); !errors.Is(ifVersionFail, ErrPreconditionFailed) {
+			t.Errorf("ERROR expected %v for a missing subscriber, got %v", ErrPreconditionFailed, ifVersionFail)
+		}
+		if _, versionFail := fixture.dut.Version(ctx, 200); !errors.Is(versionFail, ErrNotFound) {
+			t.Errorf("ERROR expected %v for the version of a missing subscriber, got %v", ErrNotFound, versionFail)
+		}
+	})
+}
+
+func TestEntityTags(t *testing.T) {
+	if tag := entityTag(7, jsonMediaType); `"7"` != tag {
+		t.Errorf("ERROR unexpected JSON ETag %s", tag)
+	}
+	if tag := entityTag(7, csvMediaType); `"7-csv"` != tag {
+		t.Errorf("ERROR unexpected CSV ETag %s", tag)
+	}
+	for _, testCase := range []struct {
+		ifMatch  string
+		expected bool
+	}{
+		{`"7"`, true},
+		{`"6", "7-xml"`, true},
+		{`*`, true},
+		{`W/"7"`, false},
+		{`"6"`, false},
+		{`7`, false},
+		{`"17"`, false},
+	} {
+		if matches := versionMatcher(testCase.ifMatch)(7); testCase.expected != matches {
+			t.Errorf("ERROR If-Match %s matched version 7: %v, expected %v", testCase.ifMatch, matches, testCase.expected)
+		}
+	}
+	for _, testCase := range []struct {
+		ifNoneMatch string
+		expected    bool
+	}{
+		{`"7"`, true},
+		{`W/"7"`, true},
+		{`"6", *`, true},
+		{`"7-csv"`, false},
+		{``, false},
+	} {
+		if matches := noneMatch(testCase.ifNoneMatch, `"7"`); testCase.expected != matches {
+			t.Errorf("ERROR If-None-Match %s matched \"7\": %v, expected %v", testCase.ifNoneMatch, matches,
+				testCase.expected)
+		}
+	}
+}
+
+func sendConditional(handler http.HandlerFunc, method string, target string, index string, header string,
+	tag string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, nil)
+	request.Header.Set(header, tag)
+	request = mux.SetURLVars(request, map[string]string{"index": index})
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	return response
+}
+
+func TestControllerPreconditions(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	response := sendBody(fixture.dut.retrieve, "GET", "/subscribers/1", "1", "", "")
+	if tag := response.Header().Get("ETag"); `"1"` != tag {
+		t.Errorf("handler returned wrong ETag: got %v want %v", tag, `"1"`)
+	}
+	for _, testCase := range []struct {
+		handler        http.HandlerFunc
+		method         string
+		target         string
+		index          string
+		header         string
+		tag            string
+		expectedStatus int
+		expectedTag    string
+	}{
+		{fixture.dut.retrieve, "GET", "/subscribers/1", "1", "If-None-Match", `"1"`, http.StatusNotModified, `"1"`},
+		{fixture.dut.retrieve, "GET", "/subscribers/1", "1", "If-None-Match", `W/"0", W/"1"`, http.StatusNotModified, `"1"`},
+		{fixture.dut.update, "PUT", "/subscribers/1?first_name=Luke", "1", "If-Match", `"1"`, http.StatusOK, `"2"`},
+		{fixture.dut.update, "PUT", "/subscribers/1?first_name=Leia", "1", "If-Match", `"1"`,
+			http.StatusPreconditionFailed, ""},
+		{fixture.dut.retrieve, "GET", "/subscribers/1", "1", "If-None-Match", `"1"`, http.StatusOK, `"2"`},
+		{fixture.dut.activate, "PATCH", "/subscribers/1?activation_flag=true", "1", "If-Match", `"2"`, http.StatusOK,
+			`"3"`},
+		{fixture.dut.transition, "PUT", "/subscribers/1/status?status=deactivated", "1", "If-Match", `"2"`,
+			http.StatusPreconditionFailed, ""},
+		{fixture.dut.transition, "PUT", "/subscribers/1/status?status=deactivated", "1", "If-Match", `"3"`, http.StatusOK,
+			`"4"`},
+		{fixture.dut.delete, "DELETE", "/subscribers/1", "1", "If-Match", `W/"4"`, http.StatusPreconditionFailed, ""},
+		{fixture.dut.delete, "DELETE", "/subscribers/200", "200", "If-Match", `*`, http.StatusPreconditionFailed, ""},
+		{fixture.dut.delete, "DELETE", "/subscribers/1", "1", "If-Match", `"3", "4"`, http.StatusNoContent, ""},
+		{fixture.dut.update, "PUT", "/subscribers/2?first_name=Han", "2", "If-None-Match", `"9"`, http.StatusOK, `"2"`},
+	} {
+		response = sendConditional(testCase.handler, testCase.method, testCase.target, testCase.index, testCase.header,
+			testCase.tag)
+		if status := response.Code; status != testCase.expectedStatus {
+			t.Errorf("handler returned wrong status code for %s %s %s: got %v want %v", testCase.method, testCase.target,
+				testCase.tag, status, testCase.expectedStatus)
+		}
+		if tag := response.Header().Get("ETag"); testCase.expectedTag != tag {
+			t.Errorf("handler returned wrong ETag for %s %s: got %v want %v", testCase.method, testCase.target, tag,
+				testCase.expectedTag)
+		}
+		if http.StatusNotModified == testCase.expectedStatus && 0 != response.Body.Len() {
+			t.Errorf("handler returned a body with 304: %s", response.Body.String())
+		}
+	}
+	if subscriber, _ := fixture.model.Retrieve(context.Background(), 2); "Han" != subscriber.FirstName {
+		t.Errorf("ERROR an unconditional update did not change subscriber %v", *subscriber)
+	}
+
+	controller := makeSubscriberController(fixture.model, MVCConfiguration{PageSize: 10, MaxPageSize: 20,
+		RequireIfMatch: true})
+	for target, handler := range map[string]http.HandlerFunc{"/subscribers/2?first_name=Rey": controller.update,
+		"/subscribers/2/status?status=active": controller.transition} {
+		response = sendBody(handler, "PUT", target, "2", "", "")
+		if status := response.Code; status != http.StatusPreconditionRequired {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v", target, status,
+				http.StatusPreconditionRequired)
+		}
+	}
+	fixture.tearDown()
+}
+
+func TestControllerModificationTimes(t *testing.T) {
+	fixture := setupSubscriberControllerTestFixture()
+	subscriber, _ := fixture.model.Retrieve(context.Background(), 1)
+	modified := lastModified(*subscriber.UpdatedAt)
+	response := sendBody(fixture.dut.retrieve, "GET", "/subscribers/1", "1", "", "")
+	if got := response.Header().Get("Last-Modified"); modified != got ||
+		!strings.Contains(response.Body.String(), `"created_at":"`+subscriber.CreatedAt.Format(time.RFC3339Nano)+`"`) {
+		t.Errorf("handler returned wrong Last-Modified: got %v %s want %v", got, response.Body.String(), modified)
+	}
+	for _, testCase := range []struct {
+		ifModifiedSince string
+		ifNoneMatch     string
+		expectedStatus  int
+	}{
+		{modified, "", http.StatusNotModified},
+		{subscriber.UpdatedAt.Add(time.Hour).Format(http.TimeFormat), "", http.StatusNotModified},
+		{subscriber.UpdatedAt.Add(-time.Hour).Format(http.TimeFormat), "", http.StatusOK},
+		{"yesterday", "", http.StatusOK},
+		{modified, `"9"`, http.StatusOK},
+		{subscriber.UpdatedAt.Add(-time.Hour).Format(http.TimeFormat), `"1"`, http.StatusNotModified},
+	} {
+		request := httptest.NewRequest("GET", "/subscribers/1", nil)
+		request.Header.Set("If-Modified-Since", testCase.ifModifiedSince)
+		if "" != testCase.ifNoneMatch {
+			request.Header.Set("If-None-Match", testCase.ifNoneMatch)
+		}
+		request = mux.SetURLVars(request, map[string]string{"index": "1"})
+		response = httptest.NewRecorder()
+		fixture.dut.retrieve(response, request)
+		if status := response.Code; status != testCase.expectedStatus {
+			t.Errorf("handler returned wrong status code for %q %q: got %v want %v", testCase.ifModifiedSince,
+				testCase.ifNoneMatch, status, testCase.expectedStatus)
+		}
+		if http.StatusNotModified == response.Code && (0 != response.Body.Len() || `"1"` != response.Header().Get("ETag")) {
+			t.Errorf("handler returned unexpected 304: %v %s", response.Header(), response.Body.String())
+		}
+	}
+	fixture.tearDown()
+}